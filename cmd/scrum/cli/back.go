@@ -0,0 +1,366 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gwydirsam/go-scrum/cmd/scrum/internal/buildtime"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanuber/columnize"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// leaveScrumBodyRE matches the scrum written by leaveScrumBody.  Scrums posted
+// by older clients have no metadata, so the body is all there is to go on.
+var leaveScrumBodyRE = regexp.MustCompile(`^(Vacation|Sick leave) until (\d{4}/\d{2}/\d{2})\s*$`)
+
+var backCmd = &cobra.Command{
+	Args:         cobra.NoArgs,
+	Use:          "back",
+	Short:        "Cancel the rest of a vacation or sick leave",
+	SilenceUsage: true,
+	Long: `Cancel the rest of a vacation or sick leave when coming back early.
+
+Every vacation or sick leave scrum from the date onward is removed, or with -i
+the date's scrum is replaced with a regular scrum.  The leave's remaining
+scrums are rewritten to end the day before.`,
+	Example: `  $ scrum back                  # Back today, remove the rest of my sick leave
+  $ scrum back -i today.md      # Back today, replace today's vacation with today.md
+  $ scrum back -D 2018-03-14 -n # Show what coming back on March 14th would change`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkRequiredFlags(cmd.Flags()); err != nil {
+			return errors.Wrap(err, "required flag missing")
+		}
+
+		return nil
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getScrumStore()
+		if err != nil {
+			return errors.Wrap(err, "unable to create a new scrum store")
+		}
+		defer dumpStoreStats(store)
+
+		backDate, err := getDateInLocation(viper.GetString(configKeyBackInputDate))
+		if err != nil {
+			return errors.Wrap(err, "unable to parse date")
+		}
+
+		username := interpolateUserEnvVar(viper.GetString(configKeyScrumUsername))
+
+		leave, err := findLeave(store, username, backDate)
+		if err != nil {
+			return err
+		}
+
+		filename := viper.GetString(configKeyBackFilename)
+		writeLeavePlan(cmd.OutOrStdout(), leave, filename)
+
+		if viper.GetBool(configKeyBackDryRun) {
+			return nil
+		}
+
+		return cancelLeave(store, leave, filename)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backCmd)
+
+	{
+		const (
+			key         = configKeyBackInputDate
+			longName    = "date"
+			shortName   = "D"
+			description = "First day back"
+		)
+		defaultValue := dateToday
+
+		flags := backCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeyBackFilename
+			longName     = "file"
+			shortName    = "i"
+			defaultValue = ""
+			description  = "File to read the first day back's scrum from"
+		)
+
+		flags := backCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeyBackDryRun
+			longName     = "dry-run"
+			shortName    = "n"
+			defaultValue = false
+			description  = "Show the scrums that would change without changing them"
+		)
+
+		flags := backCmd.Flags()
+		flags.BoolP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+}
+
+// leaveDay is a single day of a vacation or sick leave.
+type leaveDay struct {
+	date    civilDate
+	status  string
+	endDate civilDate
+	etag    string
+}
+
+// leave is the part of a user's vacation or sick leave that is cancelled by
+// coming back on backDate.
+type leave struct {
+	user     string
+	backDate civilDate
+
+	// earlier are the leave's days before backDate in order, if the leave
+	// started before backDate.
+	earlier []*leaveDay
+
+	// days are the leave's days from backDate onward.
+	days []*leaveDay
+}
+
+// getLeaveDay returns the day of a vacation or sick leave that user's scrum on
+// date records, or nil when the scrum is not a leave.  The scrum's metadata is
+// used if it has any, otherwise its body is parsed.
+func getLeaveDay(store ScrumStore, user string, date civilDate) (*leaveDay, error) {
+	ent, err := store.Stat(cmdCtx, date, user)
+	switch {
+	case err != nil && isScrumNotFoundError(err):
+		return nil, nil
+	case err != nil:
+		return nil, errors.Wrapf(err, "unable to stat %s's scrum for %s", user, date.Format(dateInputFormat))
+	}
+
+	md := ent.Metadata
+	if md.Status == "" {
+		obj, err := store.Get(cmdCtx, date, user)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get %s's scrum for %s", user, date.Format(dateInputFormat))
+		}
+
+		ent = &obj.ScrumEntry
+		md = parseLeaveScrumBody(obj.Body)
+	}
+
+	if (md.Status != scrumStatusVacation && md.Status != scrumStatusSick) || md.EndDate.IsZero() {
+		return nil, nil
+	}
+
+	return &leaveDay{
+		date:    date,
+		status:  md.Status,
+		endDate: md.EndDate,
+		etag:    ent.ETag,
+	}, nil
+}
+
+// parseLeaveScrumBody returns the status and end date of a leave scrum's body,
+// or empty metadata when body is not a leave scrum.
+func parseLeaveScrumBody(body []byte) ScrumMetadata {
+	md := leaveScrumBodyRE.FindStringSubmatch(strings.TrimSpace(string(body)))
+	if md == nil {
+		return ScrumMetadata{}
+	}
+
+	endDate, err := parseCivilDate(scrumDateLayout, md[2])
+	if err != nil {
+		return ScrumMetadata{}
+	}
+
+	status := scrumStatusVacation
+	if md[1] == "Sick leave" {
+		status = scrumStatusSick
+	}
+
+	return ScrumMetadata{Status: status, EndDate: endDate}
+}
+
+// findLeave finds the vacation or sick leave user is on on backDate, or that
+// ended the business day before, and its days from backDate onward.
+func findLeave(store ScrumStore, user string, backDate civilDate) (*leave, error) {
+	l := &leave{
+		user:     user,
+		backDate: backDate,
+	}
+
+	first, err := getLeaveDay(store, user, backDate)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := getLeaveDay(store, user, getPreviousWeekday(backDate))
+	if err != nil {
+		return nil, err
+	}
+
+	// The previous day only belongs to the leave if the leave was not over by
+	// backDate.  Every day of the leave before it records the same end date.
+	switch {
+	case last == nil, last.endDate.Before(backDate):
+	case first != nil && !first.endDate.Equal(last.endDate):
+	default:
+		for day := last; day != nil && day.endDate.Equal(last.endDate); {
+			l.earlier = append([]*leaveDay{day}, l.earlier...)
+			if day, err = getLeaveDay(store, user, getPreviousWeekday(day.date)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var endDate civilDate
+	switch {
+	case first != nil:
+		endDate = first.endDate
+	case len(l.earlier) > 0:
+		endDate = l.earlier[0].endDate
+	default:
+		return nil, errors.Errorf("%s is not on vacation or sick leave on %s", user, backDate.Format(dateInputFormat))
+	}
+
+	// Only the days of the same leave are cancelled, a later leave is left
+	// alone.
+	for _, date := range getBusinessDays(backDate, endDate) {
+		day := first
+		if !date.Equal(backDate) {
+			if day, err = getLeaveDay(store, user, date); err != nil {
+				return nil, err
+			}
+		}
+
+		if day != nil && day.endDate.Equal(endDate) {
+			l.days = append(l.days, day)
+		}
+	}
+
+	return l, nil
+}
+
+// writeLeavePlan prints the scrums cancelLeave changes.
+func writeLeavePlan(unbufOut io.Writer, l *leave, filename string) {
+	w := bufio.NewWriter(unbufOut)
+	defer w.Flush()
+
+	var rows []string
+	for _, day := range l.earlier {
+		rows = append(rows, fmt.Sprintf("%s | %s | rewrite until %s", day.date.Format(dateInputFormat), day.status, l.lastDay().Format(dateInputFormat)))
+	}
+
+	replaced := filename == ""
+	for _, day := range l.days {
+		action := "remove"
+		if filename != "" && day.date.Equal(l.backDate) {
+			action = "replace with " + filepath.Base(filename)
+			replaced = true
+		}
+
+		rows = append(rows, fmt.Sprintf("%s | %s | %s", day.date.Format(dateInputFormat), day.status, action))
+	}
+
+	if !replaced {
+		rows = append(rows, fmt.Sprintf("%s | %s | scrum %s", l.backDate.Format(dateInputFormat), scrumStatusNormal, filepath.Base(filename)))
+	}
+
+	fmt.Fprintln(w, columnize.SimpleFormat(rows))
+}
+
+// lastDay returns the last day of the leave before backDate.  It must only be
+// called when the leave started before backDate.
+func (l *leave) lastDay() civilDate {
+	return l.earlier[len(l.earlier)-1].date
+}
+
+// cancelLeave removes the leave's days from backDate onward, or replaces the
+// scrum on backDate with the scrum read from filename, and ends the leave on
+// its last remaining day.  Every remaining day is rewritten so that each of
+// them records the new end date.  Every change, including every removal, is
+// conditional on the scrum found by findLeave so a scrum changed in the
+// meantime is never clobbered.
+func cancelLeave(store ScrumStore, l *leave, filename string) error {
+	metadata := ScrumMetadata{
+		Author:        scrumAuthor(),
+		ClientVersion: buildtime.Version,
+	}
+
+	replaced := filename == ""
+	for _, day := range l.days {
+		if filename != "" && day.date.Equal(l.backDate) {
+			if err := putBackScrum(store, l, filename, PutOptions{Metadata: metadata, IfMatch: day.etag}); err != nil {
+				return err
+			}
+
+			replaced = true
+			continue
+		}
+
+		if err := unlinkScrum(store, day.date, l.user, DeleteOptions{IfMatch: day.etag}); err != nil {
+			return errors.Wrapf(err, "unable to remove scrum for %s", day.date.Format(dateInputFormat))
+		}
+	}
+
+	// The leave may have already ended when coming back, in which case there is
+	// no leave scrum to replace.
+	if !replaced {
+		if err := putBackScrum(store, l, filename, PutOptions{Metadata: metadata, IfNotExist: true}); err != nil {
+			return err
+		}
+	}
+
+	if len(l.earlier) == 0 {
+		return nil
+	}
+
+	lastDay := l.lastDay()
+	for _, day := range l.earlier {
+		md := metadata
+		md.Status = day.status
+		md.EndDate = lastDay
+		body := strings.NewReader(leaveScrumBody(day.status, lastDay))
+		if err := putScrum(store, day.date, l.user, body, PutOptions{Metadata: md, IfMatch: day.etag}); err != nil {
+			return errors.Wrapf(err, "unable to rewrite scrum for %s", day.date.Format(dateInputFormat))
+		}
+	}
+
+	log.Info().Str("username", l.user).Str("status", l.earlier[0].status).Str("until", lastDay.Format(dateInputFormat)).Msg("ended leave")
+
+	return nil
+}
+
+// putBackScrum writes the scrum read from filename for the leave's first day
+// back.
+func putBackScrum(store ScrumStore, l *leave, filename string, opts PutOptions) error {
+	body, err := readScrumFile(filename)
+	if err != nil {
+		return err
+	}
+
+	opts.Metadata.Status = scrumStatusNormal
+	if filename != "-" {
+		opts.Metadata.SourceFile = filepath.Base(filename)
+	}
+
+	return putScrum(store, l.backDate, l.user, bytes.NewReader(body), opts)
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBack(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("set", "-u", "alice", "-s", "5")
+
+	out := env.mustRun("back", "-u", "alice", "-D", "2018-03-14")
+	for _, want := range []string{"2018-03-13", "rewrite until 2018-03-13", "2018-03-16", "remove"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	for _, date := range []string{"2018/03/14", "2018/03/15", "2018/03/16"} {
+		if _, found := env.manta.Object("stor/scrum/" + date + "/alice"); found {
+			t.Errorf("scrum for %s was not removed", date)
+		}
+	}
+
+	body, _ := env.manta.Object("stor/scrum/2018/03/13/alice")
+	if got, want := string(body), "Sick leave until 2018/03/13\n"; got != want {
+		t.Errorf("last day = %q, want %q", got, want)
+	}
+
+	headers, _ := env.manta.ObjectHeaders("stor/scrum/2018/03/13/alice")
+	if got, want := headers.Get("m-scrum-end-date"), "2018-03-13"; got != want {
+		t.Errorf("last day's end date = %q, want %q", got, want)
+	}
+
+	// Every remaining day of the leave ends on the new last day.
+	body, _ = env.manta.Object("stor/scrum/2018/03/12/alice")
+	if got, want := string(body), "Sick leave until 2018/03/13\n"; got != want {
+		t.Errorf("first day = %q, want %q", got, want)
+	}
+
+	headers, _ = env.manta.ObjectHeaders("stor/scrum/2018/03/12/alice")
+	if got, want := headers.Get("m-scrum-end-date"), "2018-03-13"; got != want {
+		t.Errorf("first day's end date = %q, want %q", got, want)
+	}
+
+	if out := env.mustRun("ooo", "-D", "2018-03-14"); !strings.Contains(out, "2018-03-12 to 2018-03-13") {
+		t.Errorf("leave does not end on 2018-03-13:\n%s", out)
+	}
+}
+
+func TestBackReplace(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("set", "-u", "alice", "-v", "3")
+	input := env.writeFile("today.md", "back early\n")
+
+	env.mustRun("back", "-u", "alice", "-i", input)
+
+	body, _ := env.manta.Object("stor/scrum/2018/03/12/alice")
+	if got, want := string(body), "back early\n"; got != want {
+		t.Errorf("first day back = %q, want %q", got, want)
+	}
+
+	headers, _ := env.manta.ObjectHeaders("stor/scrum/2018/03/12/alice")
+	if got, want := headers.Get("m-scrum-status"), scrumStatusNormal; got != want {
+		t.Errorf("first day back's status = %q, want %q", got, want)
+	}
+
+	for _, date := range []string{"2018/03/13", "2018/03/14"} {
+		if _, found := env.manta.Object("stor/scrum/" + date + "/alice"); found {
+			t.Errorf("scrum for %s was not removed", date)
+		}
+	}
+}
+
+func TestBackDryRun(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("set", "-u", "alice", "-v", "2")
+
+	out := env.mustRun("back", "-u", "alice", "-D", "2018-03-13", "-n")
+	if !strings.Contains(out, "remove") {
+		t.Errorf("output does not list the removed day:\n%s", out)
+	}
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/13/alice"); !found {
+		t.Errorf("dry run removed a scrum")
+	}
+}
+
+func TestBackWithoutMetadata(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	// Older clients only recorded a leave in the scrum's body.
+	for _, date := range []string{"2018/03/12", "2018/03/13", "2018/03/14"} {
+		env.manta.PutObject("stor/scrum/"+date+"/alice", []byte("Vacation until 2018/03/14\n"))
+	}
+	env.manta.PutObject("stor/scrum/2018/03/15/alice", []byte("Vacation until 2018/03/23\n"))
+
+	env.mustRun("back", "-u", "alice", "-D", "2018-03-13")
+
+	body, _ := env.manta.Object("stor/scrum/2018/03/12/alice")
+	if got, want := string(body), "Vacation until 2018/03/12\n"; got != want {
+		t.Errorf("last day = %q, want %q", got, want)
+	}
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/14/alice"); found {
+		t.Errorf("scrum for 2018/03/14 was not removed")
+	}
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/15/alice"); !found {
+		t.Errorf("a later vacation was removed")
+	}
+}
+
+func TestBackNotOnLeave(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("did things\n"))
+
+	if _, err := env.run("back", "-u", "alice"); err == nil {
+		t.Errorf("back without a leave succeeded")
+	}
+}
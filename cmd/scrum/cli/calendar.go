@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultCalendarDays is the number of days after --since exported when
+// --until is not specified.
+const defaultCalendarDays = 90
+
+var calendarCmd = &cobra.Command{
+	Use:   "calendar",
+	Short: "Work with the holiday and out of office calendar",
+	Args:  cobra.NoArgs,
+}
+
+var calendarExportCmd = &cobra.Command{
+	Use:          "export",
+	Short:        "Export holidays and team absences as an iCalendar file",
+	SilenceUsage: true,
+	Long: `Export the holidays of every country on the team roster (or the configured
+country) and every team member's vacations and sick leaves as an iCalendar
+(.ics) file that calendar clients can import or subscribe to.`,
+	Example: `  $ scrum calendar export -o scrum.ics                                      # The next 90 days
+  $ scrum calendar export --since 2018-01-01 --until 2018-12-31 -o 2018.ics # All of 2018`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkRequiredFlags(cmd.Flags()); err != nil {
+			return errors.Wrap(err, "required flag missing")
+		}
+
+		return nil
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := getDateInLocation(viper.GetString(configKeyCalendarSince))
+		if err != nil {
+			return errors.Wrap(err, "unable to parse since date")
+		}
+
+		until := since.AddDate(0, 0, defaultCalendarDays)
+		if untilStr := viper.GetString(configKeyCalendarUntil); untilStr != "" {
+			if until, err = getDateInLocation(untilStr); err != nil {
+				return errors.Wrap(err, "unable to parse until date")
+			}
+		}
+
+		if since.After(until) {
+			return errors.Errorf("since date (%s) is after the until date (%s)", since.Format(dateInputFormat), until.Format(dateInputFormat))
+		}
+
+		store, err := getScrumStore()
+		if err != nil {
+			return errors.Wrap(err, "unable to create a new scrum store")
+		}
+		defer dumpStoreStats(store)
+
+		var w io.Writer = cmd.OutOrStdout()
+		if filename := viper.GetString(configKeyCalendarOutput); filename != "-" {
+			f, err := os.Create(filename)
+			if err != nil {
+				return errors.Wrap(err, "unable to create calendar file")
+			}
+			defer f.Close()
+
+			w = f
+		}
+
+		return exportCalendar(w, store, since, until)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(calendarCmd)
+	calendarCmd.AddCommand(calendarExportCmd)
+
+	{
+		const (
+			key         = configKeyCalendarSince
+			longName    = "since"
+			shortName   = ""
+			description = "First date to export"
+		)
+		defaultValue := dateToday
+
+		flags := calendarExportCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeyCalendarUntil
+			longName     = "until"
+			shortName    = ""
+			defaultValue = ""
+			description  = "Last date to export (defaults to 90 days after --since)"
+		)
+
+		flags := calendarExportCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeyCalendarOutput
+			longName     = "output"
+			shortName    = "o"
+			defaultValue = "-"
+			description  = "File to write the calendar to, '-' for stdout"
+		)
+
+		flags := calendarExportCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+}
+
+// calendarCountries returns the countries whose holidays are exported: every
+// country on the team roster and the configured country.
+func calendarCountries(store ScrumStore) []string {
+	countries := map[string]bool{
+		viper.GetString(configKeyCountry): true,
+	}
+
+	roster, err := loadTeamRoster(cmdCtx, store)
+	switch {
+	case err != nil && isScrumNotFoundError(err):
+	case err != nil:
+		log.Warn().Err(err).Msg("unable to load team roster, only exporting the configured country's holidays")
+	default:
+		for _, member := range roster {
+			countries[member.Country] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(countries))
+	for country := range countries {
+		sorted = append(sorted, country)
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// exportCalendar writes the holidays and team absences between since and
+// until as an iCalendar stream.
+func exportCalendar(w io.Writer, store ScrumStore, since, until civilDate) error {
+	countries := calendarCountries(store)
+
+	cal, err := loadOOOCalendar()
+	if err != nil {
+		return err
+	}
+
+	// Every day someone on the team works may have a leave scrum.
+	teamWeek := getTeamWorkWeek()
+	var workDays []civilDate
+	for date := since; !date.After(until); date = date.AddDate(0, 0, 1) {
+		if teamWeek.has(date.Weekday()) {
+			workDays = append(workDays, date)
+		}
+	}
+
+	if err := cal.addScrums(cmdCtx, store, workDays); err != nil {
+		return err
+	}
+
+	iw := newICSWriter(w, "Scrum")
+
+	var numHolidays int
+	for date := since; !date.After(until); date = date.AddDate(0, 0, 1) {
+		// Countries observing the same holiday share an event.
+		var names []string
+		observers := make(map[string][]string)
+		for _, country := range countries {
+			name, found := getCountryHolidayName(date, country)
+			if !found {
+				continue
+			}
+
+			if observers[name] == nil {
+				names = append(names, name)
+			}
+			observers[name] = append(observers[name], country)
+		}
+
+		for _, name := range names {
+			iw.event(&icsEvent{
+				UID:     icsUID("holiday", date.Format(icsDateLayout), strings.Join(observers[name], "-")),
+				Summary: name + " (" + strings.Join(observers[name], ", ") + ")",
+				Start:   date,
+				End:     date,
+			}, "Holiday")
+			numHolidays++
+		}
+	}
+
+	periods := cal.overlapping(since, until)
+	for _, p := range periods {
+		iw.event(&icsEvent{
+			UID:     icsUID("ooo", p.User, p.Start.Format(icsDateLayout)),
+			Summary: p.User + ": " + p.Status,
+			Start:   p.Start,
+			End:     p.End,
+		}, "Out of office")
+	}
+
+	if err := iw.Close(); err != nil {
+		return err
+	}
+
+	log.Info().Int("holidays", numHolidays).Int("absences", len(periods)).Msg("exported calendar")
+
+	return nil
+}
@@ -1,30 +1,40 @@
 package cli
 
 import (
+	"io/ioutil"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/circonus-labs/circonusllhist"
 	triton "github.com/joyent/triton-go"
 	"github.com/joyent/triton-go/authentication"
 	"github.com/joyent/triton-go/storage"
+	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
-// scrumClient wraps a StorateClient and a Histogram
+// scrumClient wraps a StorageClient and a Histogram.  scrumClient is the Manta
+// implementation of ScrumStore.
 type scrumClient struct {
 	*storage.StorageClient
 
 	// Time per operation (us)
 	*circonusllhist.Histogram
 
-	// Count of each operation type
-	deleteCalls uint64
-	getCalls    uint64
-	listCalls   uint64
-	putCalls    uint64
+	// Count of each operation type, updated atomically
+	deleteCalls   uint64
+	getCalls      uint64
+	headCalls     uint64
+	jobCalls      uint64
+	listCalls     uint64
+	putCalls      uint64
+	snapLinkCalls uint64
+
+	// Count of retried requests
+	retries uint64
 }
 
 func (sc *scrumClient) dumpMantaClientStats() {
@@ -40,56 +50,156 @@ func (sc *scrumClient) dumpMantaClientStats() {
 		Str("mean", (time.Duration(sc.Histogram.Mean()*float64(time.Second))).String()).
 		Str("min", (time.Duration(sc.Histogram.Min()*float64(time.Second))).String()).
 		Str("total", (time.Duration(sc.Histogram.ApproxSum()*float64(time.Second))).String()).
-		Uint64("get-calls", sc.getCalls).
-		Uint64("list-calls", sc.listCalls).
-		Uint64("put-calls", sc.putCalls).
+		Uint64("delete-calls", atomic.LoadUint64(&sc.deleteCalls)).
+		Uint64("get-calls", atomic.LoadUint64(&sc.getCalls)).
+		Uint64("head-calls", atomic.LoadUint64(&sc.headCalls)).
+		Uint64("job-calls", atomic.LoadUint64(&sc.jobCalls)).
+		Uint64("list-calls", atomic.LoadUint64(&sc.listCalls)).
+		Uint64("put-calls", atomic.LoadUint64(&sc.putCalls)).
+		Uint64("snaplink-calls", atomic.LoadUint64(&sc.snapLinkCalls)).
+		Uint64("retries", atomic.LoadUint64(&sc.retries)).
 		Msg("stats")
 }
 
-// getDateInLocation takes a given date string and parses it according to whether or not
-// the user requested UTC or Local timezone processing.
-func getDateInLocation(dateStr string) (date time.Time, err error) {
-	if viper.GetBool(configKeyUseUTC) {
-		date, err = time.Parse(dateInputFormat, dateStr)
-	} else {
-		localLocation, err := time.LoadLocation("Local")
+// timeNow returns the current time.  Tests replace timeNow in order to run
+// commands against a fixed clock.
+var timeNow = time.Now
+
+// localLocation is the Local timezone.  Tests replace localLocation in order to
+// run commands in another timezone without modifying time.Local.
+var localLocation = time.Local
+
+// getLocation returns the location used to display times and to determine
+// today's date: the timezone given with --tz, UTC if the user requested UTC,
+// or the Local timezone.
+func getLocation() (*time.Location, error) {
+	if tz := viper.GetString(configKeyTimezone); tz != "" {
+		loc, err := time.LoadLocation(tz)
 		if err != nil {
-			return time.Now(), errors.Wrap(err, "unable to load local timezone information")
+			return nil, errors.Wrapf(err, "unable to load timezone %q", tz)
 		}
 
-		date, err = time.ParseInLocation(dateInputFormat, dateStr, localLocation)
+		return loc, nil
 	}
+
+	if viper.GetBool(configKeyUseUTC) {
+		return time.UTC, nil
+	}
+
+	return localLocation, nil
+}
+
+// displayLocation returns the location times are displayed in.  The Local
+// timezone is used when that location can not be loaded.
+func displayLocation() *time.Location {
+	loc, err := getLocation()
 	if err != nil {
-		return time.Now(), errors.Wrap(err, "unable to parse date")
+		log.Debug().Err(err).Msg("unable to get location, displaying local time")
+		return localLocation
+	}
+
+	return loc
+}
+
+// displayTime returns t in the location times are displayed in.
+func displayTime(t time.Time) time.Time {
+	return t.In(displayLocation())
+}
+
+// getDayStart returns the time of day general.day-starts-at, e.g. "04:00".
+func getDayStart() (time.Duration, error) {
+	dayStartStr := viper.GetString(configKeyDayStartsAt)
+	if dayStartStr == "" {
+		return 0, nil
+	}
+
+	dayStart, err := time.Parse(dayStartsAtFormat, dayStartStr)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s, format must be: %s", configKeyDayStartsAt, dayStartsAtFormat)
+	}
+
+	return time.Duration(dayStart.Hour())*time.Hour + time.Duration(dayStart.Minute())*time.Minute, nil
+}
+
+// getToday returns the current date according to getLocation.  Until
+// general.day-starts-at it is still the previous working day, e.g. at 1am on
+// Monday with a day starting at 4am it is Friday.
+func getToday() (civilDate, error) {
+	loc, err := getLocation()
+	if err != nil {
+		return civilDate{}, err
+	}
+
+	dayStart, err := getDayStart()
+	if err != nil {
+		return civilDate{}, err
+	}
+
+	now := timeNow().In(loc)
+	today := dateOf(now)
+
+	hour, minute, _ := now.Clock()
+	if time.Duration(hour)*time.Hour+time.Duration(minute)*time.Minute < dayStart {
+		today = getPreviousWeekday(today)
+	}
+
+	return today, nil
+}
+
+// getDateInLocation takes a given date string and parses it as a civil date.
+// The date string may be a date expression relative to today, e.g.
+// "yesterday" or "last friday" (see parseDateExpr), every other date is the
+// same day wherever the user is.
+func getDateInLocation(dateStr string) (civilDate, error) {
+	today, err := getToday()
+	if err != nil {
+		return civilDate{}, err
+	}
+
+	date, err := parseDateExpr(dateStr, today)
+	if err != nil {
+		return civilDate{}, errors.Wrap(err, "unable to parse date")
 	}
 
 	return date, nil
 }
 
-// getNextWeekday returns the next weekday.
-func getNextWeekday(scrumDate time.Time) time.Time {
+// getDateRangeInLocation parses a date string that may be a range of dates,
+// e.g. "2018-03-01..2018-03-09" or "this week" (see parseDateRangeExpr).  A
+// single date is returned as both the first and last date of the range.
+func getDateRangeInLocation(dateStr string) (since, until civilDate, err error) {
+	today, err := getToday()
+	if err != nil {
+		return since, until, err
+	}
+
+	if since, until, err = parseDateRangeExpr(dateStr, today); err != nil {
+		return since, until, errors.Wrap(err, "unable to parse date")
+	}
+
+	return since, until, nil
+}
+
+// getNextWeekday returns the next day of the scrum user's work week.
+func getNextWeekday(scrumDate civilDate) civilDate {
 	return getWeekday(scrumDate, true)
 }
 
-// getPreviousWeekday returns the previous weekday.
-func getPreviousWeekday(scrumDate time.Time) time.Time {
+// getPreviousWeekday returns the previous day of the scrum user's work week.
+func getPreviousWeekday(scrumDate civilDate) civilDate {
 	return getWeekday(scrumDate, false)
 }
 
 func getScrumClient() (*scrumClient, error) {
-	input := authentication.SSHAgentSignerInput{
-		KeyID:       viper.GetString(configKeyMantaKeyID),
-		AccountName: interpolateMantaUserEnvVar(viper.GetString(configKeyMantaAccount)),
-	}
-	sshKeySigner, err := authentication.NewSSHAgentSigner(input)
+	signer, err := getMantaSigner()
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to create new SSH agent signer")
+		return nil, err
 	}
 
 	tsc, err := storage.NewClient(&triton.ClientConfig{
 		MantaURL:    viper.GetString(configKeyMantaURL),
 		AccountName: interpolateMantaUserEnvVar(viper.GetString(configKeyScrumAccount)),
-		Signers:     []authentication.Signer{sshKeySigner},
+		Signers:     []authentication.Signer{signer},
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to create a new manta client")
@@ -101,17 +211,54 @@ func getScrumClient() (*scrumClient, error) {
 	}, nil
 }
 
-// getWeekday is the internal helper function that either adds or subtracts a
-// weekday and tests to see if the next day in the sequence is a holiday or not
-// for the given country.
-//
-// TODO: teach getWeekday to take in to consideration a vacation schedule.
-func getWeekday(scrumDate time.Time, nextDay bool) time.Time {
-	holidays := getHolidays()
+// getMantaSigner returns a signer for Manta requests.  If a private key file
+// was configured the key is read directly, otherwise the key is looked up in
+// ssh-agent(1).
+func getMantaSigner() (authentication.Signer, error) {
+	keyID := viper.GetString(configKeyMantaKeyID)
+	accountName := interpolateMantaUserEnvVar(viper.GetString(configKeyMantaAccount))
+
+	if rawFilename := viper.GetString(configKeyMantaKeyMaterial); rawFilename != "" {
+		filename, err := homedir.Expand(rawFilename)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to find a user's home directory")
+		}
+
+		keyMaterial, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read private key")
+		}
+
+		signer, err := authentication.NewPrivateKeySigner(authentication.PrivateKeySignerInput{
+			KeyID:              keyID,
+			PrivateKeyMaterial: keyMaterial,
+			AccountName:        accountName,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create new private key signer")
+		}
+
+		return signer, nil
+	}
+
+	signer, err := authentication.NewSSHAgentSigner(authentication.SSHAgentSignerInput{
+		KeyID:       keyID,
+		AccountName: accountName,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create new SSH agent signer")
+	}
+
+	return signer, nil
+}
 
+// getWeekday is the internal helper function that either adds or subtracts a
+// day of the scrum user's work week and tests to see if the next day in the
+// sequence is a holiday or not for the given country.  getUserWeekday also
+// skips the days a user is out of the office.
+func getWeekday(scrumDate civilDate, nextDay bool) civilDate {
 	myCountry := viper.GetString(configKeyCountry)
 
-NEXT_DATE:
 	for {
 		if nextDay {
 			scrumDate = scrumDate.AddDate(0, 0, 1)
@@ -119,35 +266,50 @@ NEXT_DATE:
 			scrumDate = scrumDate.AddDate(0, 0, -1)
 		}
 
-		switch scrumDate.Weekday() {
-		case time.Monday, time.Tuesday, time.Wednesday,
-			time.Thursday, time.Friday:
-
-			holiday, found := holidays[scrumDate]
-			if !found {
-				return scrumDate
-			}
-
-			countries := holiday.getCountries()
-			for _, country := range countries {
-				// Search for a date until my country is not in observance of a holiday.
-				if country == myCountry {
-					holidayName, err := holiday.getCountryHoliday(country)
-					if err != nil {
-						log.Warn().Err(err).Msg("unable to get a country's specific holiday")
-					}
-
-					log.Info().Str("country", country).Str("holiday", holidayName).Str("date", scrumDate.Format(dateInputFormat)).Msg("skipping holiday")
-					continue NEXT_DATE
-				}
-			}
-
-			// myCountry isn't observing a holiday on this day
-			return scrumDate
+		if !isWorkDay(scrumDate) {
+			continue
+		}
+
+		// Search for a date until my country is not in observance of a holiday.
+		if holidayName, found := getCountryHolidayName(scrumDate, myCountry); found {
+			log.Info().Str("country", myCountry).Str("holiday", holidayName).Str("date", scrumDate.Format(dateInputFormat)).Msg("skipping holiday")
+			continue
 		}
+
+		return scrumDate
+	}
+}
+
+// isBusinessDay returns true if date is a day of the scrum user's work week
+// that is not a holiday in the configured country.
+func isBusinessDay(date civilDate) bool {
+	if !isWorkDay(date) {
+		return false
+	}
+
+	_, found := getCountryHolidayName(date, viper.GetString(configKeyCountry))
+	return !found
+}
+
+// addBusinessDays returns the last day of a span of n business days starting on
+// start, which counts as the first day if it is a business day.
+func addBusinessDays(start civilDate, n int) civilDate {
+	date := start
+	if !isBusinessDay(date) {
+		date = getNextWeekday(date)
+	}
+
+	for i := 1; i < n; i++ {
+		date = getNextWeekday(date)
 	}
 
-	panic("unpossible")
+	return date
+}
+
+// getCountryHolidayName returns the name of the holiday country observes on
+// date, if any.
+func getCountryHolidayName(date civilDate, country string) (string, bool) {
+	return getHolidays(date).lookup(date, country)
 }
 
 func interpolateMantaUserEnvVar(val string) string {
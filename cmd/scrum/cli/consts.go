@@ -3,16 +3,52 @@ package cli
 const (
 	dateInputFormat = "2006-01-02"
 
+	// dayStartsAtFormat is the format of general.day-starts-at.
+	dayStartsAtFormat = "15:04"
+
+	// dateToday is the default date input and is resolved to the current date
+	// at the time the command runs.
+	dateToday = "today"
+
+	configKeyBackDryRun    = "back.dry-run"
+	configKeyBackFilename  = "back.input-filename"
+	configKeyBackInputDate = "back.date"
+
+	configKeyCalendarOutput = "calendar.output"
+	configKeyCalendarSince  = "calendar.since"
+	configKeyCalendarUntil  = "calendar.until"
+
 	configKeyGetAll       = "get.all"
 	configKeyGetHighlight = "highlight"
 	configKeyGetInputDate = "get.date"
+	configKeyGetOutput    = "get.output"
 	configKeyGetTomorrow  = "get.tomorrow"
 	configKeyGetYesterday = "get.yesterday"
 
-	configKeyHolidays = "holidays"
-	configKeyCountry  = "general.country"
-	configKeyUsePager = "general.use-pager"
-	configKeyUseUTC   = "general.utc"
+	configKeyHistorySince = "history.since"
+	configKeyHistoryUntil = "history.until"
+
+	// configKeyHolidayListYear is not under "holidays", which holds the
+	// holidays themselves.
+	configKeyHolidayListYear = "holiday-list.year"
+
+	configKeyHolidays    = "holidays"
+	configKeyConcurrency = "general.concurrency"
+	configKeyCountry     = "general.country"
+	configKeyDayStartsAt = "general.day-starts-at"
+	configKeyTimezone    = "general.tz"
+	configKeyUsePager    = "general.use-pager"
+	configKeyUseUTC      = "general.utc"
+	configKeyWorkWeek    = "general.work-week"
+
+	configKeyMissingInputDate = "missing.date"
+	configKeyMissingManager   = "missing.manager"
+	configKeyMissingYesterday = "missing.yesterday"
+
+	configKeyOOOFile      = "ooo.file"
+	configKeyOOOInputDate = "ooo.date"
+
+	configKeyRollupInputDate = "rollup.date"
 
 	configKeyScrumAccount  = "scrum.manta-account"
 	configKeyScrumUsername = "scrum.username"
@@ -20,6 +56,7 @@ const (
 	configKeyInitFilename = "init.config-file"
 
 	configKeyListInputDate = "list.date"
+	configKeyListOutput    = "list.output"
 	configKeyListTomorrow  = "list.tomorrow"
 	configKeyListUsers     = "list.mode"
 	configKeyListUsersAll  = "list.opt-all"
@@ -31,22 +68,40 @@ const (
 	configKeyLogStats     = "log.stats"
 	configKeyLogTermColor = "log.use-color"
 
-	configKeyMantaAccount = "manta.account"
-	configKeyMantaKeyID   = "manta.key-id"
-	configKeyMantaTimeout = "manta.timeout"
-	configKeyMantaURL     = "manta.url"
-	configKeyMantaUser    = "manta.user"
+	configKeyMantaAccount         = "manta.account"
+	configKeyMantaKeyID           = "manta.key-id"
+	configKeyMantaKeyMaterial     = "manta.key-material"
+	configKeyMantaRetryAttempts   = "manta.retry-attempts"
+	configKeyMantaRetryBackoff    = "manta.retry-backoff"
+	configKeyMantaRetryMaxBackoff = "manta.retry-max-backoff"
+	configKeyMantaTimeout         = "manta.timeout"
+	configKeyMantaURL             = "manta.url"
+	configKeyMantaUser            = "manta.user"
+
+	configKeySearchRemote = "search.remote"
+	configKeySearchSince  = "search.since"
+	configKeySearchUntil  = "search.until"
+	configKeySearchUsers  = "search.users"
 
 	configKeySetFilename     = "set.input-filename"
 	configKeySetForce        = "set.force"
 	configKeySetInputDate    = "set.date"
+	configKeySetLeave        = "set.leave"
 	configKeySetNumDays      = "set.num-days"
 	configKeySetSickDays     = "set.sick-days"
 	configKeySetTomorrow     = "set.tomorrow"
 	configKeySetUnlinkDay    = "set.unlink-day"
+	configKeySetUntil        = "set.until"
 	configKeySetVacationDays = "set.vacation-days"
 	configKeySetYesterday    = "set.yesterday"
 
+	configKeyTeamFile = "team.file"
+
+	configKeyWorkWeeks = "work-weeks"
+
+	configKeyStorageBackend   = "storage.backend"
+	configKeyStorageDirectory = "storage.directory"
+
 	mtimeFormat   = "2006-01-02 15:04:05"
 	mtimeFormatTZ = "2006-01-02 15:04:05 MST"
 
@@ -62,6 +117,7 @@ const (
 
 var usernameActionMap = map[string]_UsernameAction{
 	"all":          _Ignore,
+	"all.html":     _Ignore,
 	"all1999.html": _Ignore,
 	"rollup":       _Ignore,
 }
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"time"
+)
+
+// civilDate is a day on the calendar, independent of any location.  Scrums
+// and holidays are filed by civil date: the scrum for 2018-03-12 is the same
+// object whether it is looked up from Tokyo or San Francisco, only "today"
+// depends on where the user is.  A civilDate is comparable and can be used
+// as a map key.
+type civilDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+// newCivilDate returns the civil date of year, month and day.  Like time.Date,
+// values outside of their usual range are normalized, e.g. March 0th is the
+// last day of February.
+func newCivilDate(year int, month time.Month, day int) civilDate {
+	return dateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// dateOf returns the civil date of t in t's location.
+func dateOf(t time.Time) civilDate {
+	year, month, day := t.Date()
+	return civilDate{year: year, month: month, day: day}
+}
+
+// parseCivilDate parses a date formatted according to layout.  Any time of day
+// or zone in the layout is ignored.
+func parseCivilDate(layout, value string) (civilDate, error) {
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return civilDate{}, err
+	}
+
+	return dateOf(t), nil
+}
+
+// Year returns the year of d.
+func (d civilDate) Year() int {
+	return d.year
+}
+
+// Month returns the month of the year of d.
+func (d civilDate) Month() time.Month {
+	return d.month
+}
+
+// Day returns the day of the month of d.
+func (d civilDate) Day() int {
+	return d.day
+}
+
+// In returns the start of d in loc.
+func (d civilDate) In(loc *time.Location) time.Time {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, loc)
+}
+
+// time returns the start of d in UTC, which every calculation on civil dates
+// is made in so that no day is skipped or repeated by a DST transition.
+func (d civilDate) time() time.Time {
+	return d.In(time.UTC)
+}
+
+// AddDate returns the civil date years, months and days after d, normalized
+// like time.Time.AddDate.
+func (d civilDate) AddDate(years, months, days int) civilDate {
+	return dateOf(d.time().AddDate(years, months, days))
+}
+
+// Weekday returns the day of the week of d.
+func (d civilDate) Weekday() time.Weekday {
+	return d.time().Weekday()
+}
+
+// IsZero returns true if d is the zero civilDate, which is not a valid date.
+func (d civilDate) IsZero() bool {
+	return d == civilDate{}
+}
+
+// Before returns true if d is before other.
+func (d civilDate) Before(other civilDate) bool {
+	if d.year != other.year {
+		return d.year < other.year
+	}
+
+	if d.month != other.month {
+		return d.month < other.month
+	}
+
+	return d.day < other.day
+}
+
+// After returns true if d is after other.
+func (d civilDate) After(other civilDate) bool {
+	return other.Before(d)
+}
+
+// Equal returns true if d and other are the same day.
+func (d civilDate) Equal(other civilDate) bool {
+	return d == other
+}
+
+// Format returns d formatted according to a date layout, e.g.
+// dateInputFormat or scrumDateLayout.
+func (d civilDate) Format(layout string) string {
+	return d.time().Format(layout)
+}
+
+// String returns d in dateInputFormat.
+func (d civilDate) String() string {
+	return d.Format(dateInputFormat)
+}
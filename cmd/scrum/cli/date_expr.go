@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// dateRangeSeparator separates the first and last date of a range of dates,
+// e.g. "2018-03-01..2018-03-09".
+const dateRangeSeparator = ".."
+
+// dateExprHelp lists the date expressions accepted by parseDateExpr.
+const dateExprHelp = `YYYY-MM-DD, "today", "yesterday", "tomorrow", "last friday", "next monday", "-3" business days or "2w ago"`
+
+var (
+	// dateBusinessDaysRE matches a number of business days before or after
+	// today, e.g. "-3" or "+2".
+	dateBusinessDaysRE = regexp.MustCompile(`^([+-])\s*(\d+)$`)
+
+	// dateAgoRE matches a number of days or weeks before today, e.g. "2w ago"
+	// or "3 days ago".
+	dateAgoRE = regexp.MustCompile(`^(\d+)\s*(d|days?|w|weeks?)\s+ago$`)
+
+	// dateRelativeWeekdayRE matches the closest day of the week before or after
+	// today, e.g. "last friday" or "next mon".
+	dateRelativeWeekdayRE = regexp.MustCompile(`^(last|next)\s+([a-z]+)$`)
+
+	// dateAmbiguousRE matches dates whose order of day and month is unclear,
+	// e.g. "03/04/2018" or "3-4".
+	dateAmbiguousRE = regexp.MustCompile(`^\d{1,2}[/.-]\d{1,2}([/.-]\d{2,4})?$`)
+)
+
+// normalizeDateExpr lower cases a date expression and collapses its
+// whitespace.
+func normalizeDateExpr(expr string) string {
+	return strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+}
+
+// parseDateExpr resolves a date expression relative to today.  "yesterday",
+// "tomorrow" and a number of business days, e.g. "-3", skip weekends and
+// holidays like -y and -t.  "last friday", "next monday" and "2w ago" count
+// calendar days.
+func parseDateExpr(expr string, today civilDate) (civilDate, error) {
+	expr = normalizeDateExpr(expr)
+	switch expr {
+	case dateToday:
+		return today, nil
+	case "yesterday":
+		return getPreviousWeekday(today), nil
+	case "tomorrow":
+		return getNextWeekday(today), nil
+	}
+
+	if strings.Contains(expr, dateRangeSeparator) || strings.HasSuffix(expr, " week") {
+		return civilDate{}, errors.Errorf("%q is a range of dates, a single date is required", expr)
+	}
+
+	if md := dateBusinessDaysRE.FindStringSubmatch(expr); md != nil {
+		n, err := strconv.Atoi(md[2])
+		if err != nil {
+			return civilDate{}, errors.Wrapf(err, "invalid number of business days in %q", expr)
+		}
+
+		date := today
+		for i := 0; i < n; i++ {
+			date = getWeekday(date, md[1] == "+")
+		}
+
+		return date, nil
+	}
+
+	if md := dateAgoRE.FindStringSubmatch(expr); md != nil {
+		n, err := strconv.Atoi(md[1])
+		if err != nil {
+			return civilDate{}, errors.Wrapf(err, "invalid number of days in %q", expr)
+		}
+
+		if strings.HasPrefix(md[2], "w") {
+			n *= 7
+		}
+
+		return today.AddDate(0, 0, -n), nil
+	}
+
+	if md := dateRelativeWeekdayRE.FindStringSubmatch(expr); md != nil {
+		weekday, found := workWeekDays[md[2]]
+		if !found {
+			return civilDate{}, errors.Errorf("invalid day of the week %q in %q", md[2], expr)
+		}
+
+		if md[1] == "last" {
+			offset := (int(today.Weekday()) - int(weekday) + 7) % 7
+			if offset == 0 {
+				offset = 7
+			}
+
+			return today.AddDate(0, 0, -offset), nil
+		}
+
+		offset := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if offset == 0 {
+			offset = 7
+		}
+
+		return today.AddDate(0, 0, offset), nil
+	}
+
+	if _, found := workWeekDays[expr]; found {
+		return civilDate{}, errors.Errorf(`ambiguous date %q, use "last %s" or "next %s"`, expr, expr, expr)
+	}
+
+	if dateAmbiguousRE.MatchString(expr) {
+		return civilDate{}, errors.Errorf("ambiguous date %q, format must be: %s", expr, dateInputFormat)
+	}
+
+	date, err := parseCivilDate(dateInputFormat, expr)
+	if err != nil {
+		return civilDate{}, errors.Errorf("invalid date %q, use %s", expr, dateExprHelp)
+	}
+
+	return date, nil
+}
+
+// parseDateRangeExpr resolves a date expression or a range of dates relative
+// to today.  A range is two date expressions separated by "..", e.g.
+// "2018-03-01..2018-03-09" or "last monday..yesterday", or one of "this
+// week", "last week" or "next week", which run for seven days from the start
+// of the scrum user's work week, e.g. from Monday to Sunday.  A single date is
+// a range of one day.
+func parseDateRangeExpr(expr string, today civilDate) (civilDate, civilDate, error) {
+	expr = normalizeDateExpr(expr)
+	switch expr {
+	case "this week", "last week", "next week":
+		first := getMyWorkWeek().weekOf(today)
+		switch {
+		case strings.HasPrefix(expr, "last"):
+			first = first.AddDate(0, 0, -7)
+		case strings.HasPrefix(expr, "next"):
+			first = first.AddDate(0, 0, 7)
+		}
+
+		return first, first.AddDate(0, 0, 6), nil
+	}
+
+	parts := strings.Split(expr, dateRangeSeparator)
+	switch {
+	case len(parts) == 1:
+		date, err := parseDateExpr(expr, today)
+		return date, date, err
+	case len(parts) > 2:
+		return civilDate{}, civilDate{}, errors.Errorf("invalid range of dates %q, format must be: first%slast", expr, dateRangeSeparator)
+	case strings.TrimSpace(parts[0]) == "":
+		return civilDate{}, civilDate{}, errors.Errorf("range of dates %q has no first date", expr)
+	case strings.TrimSpace(parts[1]) == "":
+		return civilDate{}, civilDate{}, errors.Errorf("range of dates %q has no last date", expr)
+	}
+
+	since, err := parseDateExpr(parts[0], today)
+	if err != nil {
+		return civilDate{}, civilDate{}, errors.Wrap(err, "unable to parse the first date of the range")
+	}
+
+	until, err := parseDateExpr(parts[1], today)
+	if err != nil {
+		return civilDate{}, civilDate{}, errors.Wrap(err, "unable to parse the last date of the range")
+	}
+
+	if since.After(until) {
+		return civilDate{}, civilDate{}, errors.Errorf("first date of the range (%s) is after the last date (%s)", since, until)
+	}
+
+	return since, until, nil
+}
@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDateExpr(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	monday := newCivilDate(2018, time.March, 12)
+	for _, test := range []struct {
+		expr  string
+		today civilDate
+		want  string
+	}{
+		{"today", monday, "2018-03-12"},
+		{"Yesterday", monday, "2018-03-09"},
+		{"tomorrow", monday, "2018-03-13"},
+		{"-3", monday, "2018-03-07"},
+		{"+5", monday, "2018-03-19"},
+		{"2w ago", monday, "2018-02-26"},
+		{"3  days ago", monday, "2018-03-09"},
+		{"last friday", monday, "2018-03-09"},
+		{"last monday", monday, "2018-03-05"},
+		{"next Mon", monday, "2018-03-19"},
+		{"next fri", monday, "2018-03-16"},
+		{"2018-03-01", monday, "2018-03-01"},
+
+		// Business days skip holidays: 2018-04-13 is Wellbeing Day.
+		{"yesterday", newCivilDate(2018, time.April, 16), "2018-04-12"},
+		{"-2", newCivilDate(2018, time.April, 16), "2018-04-11"},
+		{"last friday", newCivilDate(2018, time.April, 16), "2018-04-13"},
+	} {
+		date, err := parseDateExpr(test.expr, test.today)
+		if err != nil {
+			t.Errorf("unable to parse %q: %v", test.expr, err)
+			continue
+		}
+
+		if got := date.String(); got != test.want {
+			t.Errorf("%q on %s = %s, want %s", test.expr, test.today, got, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		expr string
+		want string
+	}{
+		{"friday", `use "last friday" or "next friday"`},
+		{"03/04/2018", "ambiguous date"},
+		{"3-4", "ambiguous date"},
+		{"2018-03-01..2018-03-09", "a single date is required"},
+		{"this week", "a single date is required"},
+		{"last funday", "invalid day of the week"},
+		{"soon", "invalid date"},
+		{"2018-02-30", "invalid date"},
+	} {
+		if _, err := parseDateExpr(test.expr, monday); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q error = %v, want %q", test.expr, err, test.want)
+		}
+	}
+}
+
+func TestParseDateRangeExpr(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	// Wednesday
+	today := newCivilDate(2018, time.March, 14)
+	for _, test := range []struct {
+		expr  string
+		since string
+		until string
+	}{
+		{"this week", "2018-03-12", "2018-03-18"},
+		{"last week", "2018-03-05", "2018-03-11"},
+		{"next week", "2018-03-19", "2018-03-25"},
+		{"2018-03-01..2018-03-09", "2018-03-01", "2018-03-09"},
+		{"last monday .. yesterday", "2018-03-12", "2018-03-13"},
+		{"yesterday", "2018-03-13", "2018-03-13"},
+	} {
+		since, until, err := parseDateRangeExpr(test.expr, today)
+		if err != nil {
+			t.Errorf("unable to parse %q: %v", test.expr, err)
+			continue
+		}
+
+		if since.String() != test.since || until.String() != test.until {
+			t.Errorf("%q = %s..%s, want %s..%s", test.expr, since, until, test.since, test.until)
+		}
+	}
+
+	for _, test := range []struct {
+		expr string
+		want string
+	}{
+		{"2018-03-09..2018-03-01", "is after the last date"},
+		{"2018-03-01..", "has no last date"},
+		{"..2018-03-01", "has no first date"},
+		{"2018-03-01..2018-03-05..2018-03-09", "invalid range of dates"},
+		{"2018-03-01..friday", "ambiguous date"},
+	} {
+		if _, _, err := parseDateRangeExpr(test.expr, today); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q error = %v, want %q", test.expr, err, test.want)
+		}
+	}
+}
+
+func TestGetDateRange(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/08/alice", []byte("thursday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/09/alice", []byte("friday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/09/bob", []byte("bob on friday\n"))
+
+	out := env.mustRun("get", "-u", "alice", "-D", "last thursday..yesterday")
+	var last int
+	for _, want := range []string{"2018-03-08 (Thursday)", "thursday", "2018-03-09 (Friday)", "friday"} {
+		i := strings.Index(out[last:], want)
+		if i == -1 {
+			t.Fatalf("output is missing %q after offset %d:\n%s", want, last, out)
+		}
+		last += i + len(want)
+	}
+
+	out = env.mustRun("get", "-a", "-D", "2018-03-08..2018-03-11")
+	last = 0
+	for _, want := range []string{"2018-03-08 (Thursday)", "thursday", "2018-03-09 (Friday)", "friday", "bob on friday"} {
+		i := strings.Index(out[last:], want)
+		if i == -1 {
+			t.Fatalf("output is missing %q after offset %d:\n%s", want, last, out)
+		}
+		last += i + len(want)
+	}
+
+	if strings.Contains(out, "2018-03-10") {
+		t.Errorf("output includes the weekend:\n%s", out)
+	}
+
+	// Nobody has scrummed for the rest of the week yet.
+	out = env.mustRun("get", "-a", "-D", "this week")
+	for _, want := range []string{"2018-03-12 (Monday)", "2018-03-16 (Friday)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	out = env.mustRun("get", "-u", "alice", "-D", "-1")
+	if got, want := out, "friday\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"get", "-t", "-D", "this week"}, "can not be used with a range of dates"},
+		{[]string{"get", "-D", "2018-03-10..2018-03-11"}, "no business days"},
+		{[]string{"set", "-D", "this week", "-i", env.writeFile("today.md", "did things\n")}, "a single date is required"},
+	} {
+		if _, err := env.run(test.args...); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v error = %v, want %q", test.args, err, test.want)
+		}
+	}
+}
+
+func TestListDateRange(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/09/alice", []byte("friday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/bob", []byte("monday\n"))
+
+	out := env.mustRun("list", "-1", "-D", "last friday..today")
+	if got, want := out, "2018-03-09 (Friday)\nalice\n2018-03-12 (Monday)\nbob\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	// Nobody has scrummed for the rest of the week yet.
+	out = env.mustRun("list", "-1", "-D", "this week")
+	if got, want := out, "2018-03-12 (Monday)\nbob\n2018-03-13 (Tuesday)\n2018-03-14 (Wednesday)\n2018-03-15 (Thursday)\n2018-03-16 (Friday)\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestCivilDate(t *testing.T) {
+	d := newCivilDate(2018, time.March, 0)
+	if got, want := d.String(), "2018-02-28"; got != want {
+		t.Errorf("March 0th = %s, want %s", got, want)
+	}
+
+	for _, test := range []struct {
+		date civilDate
+		want string
+	}{
+		{d.AddDate(0, 0, 1), "2018-03-01"},
+		{d.AddDate(0, 1, 0), "2018-03-28"},
+		{newCivilDate(2018, time.December, 31).AddDate(0, 0, 1), "2019-01-01"},
+
+		// March 11th 2018 is 23 hours long in San Francisco.
+		{newCivilDate(2018, time.March, 10).AddDate(0, 0, 2), "2018-03-12"},
+	} {
+		if got := test.date.String(); got != test.want {
+			t.Errorf("date = %s, want %s", got, test.want)
+		}
+	}
+
+	monday, tuesday := newCivilDate(2018, time.March, 12), newCivilDate(2018, time.March, 13)
+	switch {
+	case !monday.Before(tuesday), monday.After(tuesday), !tuesday.After(monday):
+		t.Errorf("%s and %s are out of order", monday, tuesday)
+	case monday.Weekday() != time.Monday:
+		t.Errorf("%s is a %s", monday, monday.Weekday())
+	case !monday.Equal(dateOf(time.Date(2018, time.March, 12, 23, 0, 0, 0, time.FixedZone("PDT", -7*60*60)))):
+		t.Errorf("%s is not the date of a time late on %s", monday, monday)
+	case monday.Format(scrumDateLayout) != "2018/03/12":
+		t.Errorf("%s is formatted as %s", monday, monday.Format(scrumDateLayout))
+	}
+}
+
+func TestCivilDateAcrossTimezones(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	// 20:00 UTC is Tuesday morning in Tokyo and Monday noon in San Francisco.
+	env.now = time.Date(2018, time.March, 12, 20, 0, 0, 0, time.UTC)
+
+	viper.Set(configKeyUseUTC, false)
+	local := localLocation
+	defer func() { localLocation = local }()
+
+	for _, test := range []struct {
+		loc   *time.Location
+		today string
+	}{
+		{time.FixedZone("JST", 9*60*60), "2018-03-13"},
+		{time.FixedZone("PDT", -7*60*60), "2018-03-12"},
+	} {
+		localLocation = test.loc
+
+		today, err := getDateInLocation(dateToday)
+		if err != nil {
+			t.Fatalf("unable to get today: %v", err)
+		}
+
+		if got := today.String(); got != test.today {
+			t.Errorf("today in %s = %s, want %s", test.loc, got, test.today)
+		}
+
+		// Any other date is the same scrum and the same holiday everywhere.
+		date, err := getDateInLocation("2018-07-04")
+		if err != nil {
+			t.Fatalf("unable to parse date: %v", err)
+		}
+
+		if got, want := mantaScrumPath(date, "alice"), "stor/scrum/2018/07/04/alice"; got != want {
+			t.Errorf("scrum path in %s = %s, want %s", test.loc, got, want)
+		}
+
+		if name, found := getCountryHolidayName(date, "us"); !found || name != "Independence Day" {
+			t.Errorf("us holiday on %s in %s = %q", date, test.loc, name)
+		}
+
+		input := env.writeFile("today.md", "did things\n")
+		env.mustRun("set", "-u", "alice", "-D", "2018-03-14", "-i", input, "-f")
+		if _, found := env.manta.Object("stor/scrum/2018/03/14/alice"); !found {
+			t.Errorf("scrum for 2018-03-14 was not written in %s", test.loc)
+		}
+	}
+}
+
+func TestDayStartsAt(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyDayStartsAt, "04:00")
+	defer viper.Set(configKeyDayStartsAt, nil)
+
+	input := env.writeFile("today.md", "did things\n")
+	for _, test := range []struct {
+		now  time.Time
+		path string
+	}{
+		// Still Friday's scrum at 1am on Monday.
+		{time.Date(2018, time.March, 12, 1, 0, 0, 0, time.UTC), "stor/scrum/2018/03/09/alice"},
+
+		// Still Monday's scrum at 1:30am on Tuesday.
+		{time.Date(2018, time.March, 13, 1, 30, 0, 0, time.UTC), "stor/scrum/2018/03/12/alice"},
+		{time.Date(2018, time.March, 13, 4, 0, 0, 0, time.UTC), "stor/scrum/2018/03/13/alice"},
+	} {
+		env.now = test.now
+		env.mustRun("set", "-u", "alice", "-i", input)
+		if _, found := env.manta.Object(test.path); !found {
+			t.Errorf("scrum set at %s was not written to %s", test.now.Format(mtimeFormat), test.path)
+		}
+	}
+
+	viper.Set(configKeyDayStartsAt, "4am")
+	if _, err := env.run("get"); err == nil || !strings.Contains(err.Error(), configKeyDayStartsAt) {
+		t.Errorf("error = %v, want an invalid %s", err, configKeyDayStartsAt)
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	// 2am UTC on Monday is 10pm on Sunday in Toronto.
+	env.now = time.Date(2018, time.March, 12, 2, 0, 0, 0, time.UTC)
+	env.manta.PutObject("stor/scrum/2018/03/11/alice", []byte("sunday\n"))
+
+	out := env.mustRun("get", "--tz", "America/Toronto", "-u", "alice")
+	if got, want := out, "sunday\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	env.now = time.Date(2018, time.March, 12, 16, 0, 0, 0, time.UTC)
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("monday\n"))
+
+	out = env.mustRun("list", "-a", "--tz", "America/Toronto")
+	for _, want := range []string{"MTIME (EDT)", "2018-03-12 12:00:00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := env.run("get", "--tz", "Mars/Olympus_Mons"); err == nil || !strings.Contains(err.Error(), "Mars/Olympus_Mons") {
+		t.Errorf("error = %v, want an unknown timezone", err)
+	}
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// scrumRequest identifies a single scrum to fetch.
+type scrumRequest struct {
+	date civilDate
+	user string
+}
+
+// scrumResult is the outcome of fetching a scrumRequest.  obj and err must not
+// be read until ready has been closed.
+type scrumResult struct {
+	scrumRequest
+
+	obj   *ScrumObject
+	err   error
+	ready chan struct{}
+}
+
+// wait blocks until the result is ready and returns the fetched scrum.
+func (r *scrumResult) wait() (*ScrumObject, error) {
+	<-r.ready
+	return r.obj, r.err
+}
+
+// getConcurrency returns the configured number of concurrent fetches.
+func getConcurrency() int {
+	concurrency := viper.GetInt(configKeyConcurrency)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return concurrency
+}
+
+// fetchScrums fetches every request using a bounded pool of workers.  The
+// results are returned immediately in the same order as reqs and become ready
+// as each fetch completes, which lets callers render results in order while
+// later requests are still in flight.
+func fetchScrums(ctx context.Context, store ScrumStore, reqs []scrumRequest, concurrency int) []*scrumResult {
+	return runScrumRequests(reqs, concurrency, func(req scrumRequest) (*ScrumObject, error) {
+		return store.Get(ctx, req.date, req.user)
+	})
+}
+
+// statScrums is like fetchScrums but only fetches the metadata of each scrum.
+// The Body of every result is empty.
+func statScrums(ctx context.Context, store ScrumStore, reqs []scrumRequest, concurrency int) []*scrumResult {
+	return runScrumRequests(reqs, concurrency, func(req scrumRequest) (*ScrumObject, error) {
+		ent, err := store.Stat(ctx, req.date, req.user)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ScrumObject{ScrumEntry: *ent}, nil
+	})
+}
+
+// runScrumRequests calls fn for every request using a bounded pool of
+// workers.
+func runScrumRequests(reqs []scrumRequest, concurrency int, fn func(scrumRequest) (*ScrumObject, error)) []*scrumResult {
+	results := make([]*scrumResult, 0, len(reqs))
+	for _, req := range reqs {
+		results = append(results, &scrumResult{
+			scrumRequest: req,
+			ready:        make(chan struct{}),
+		})
+	}
+
+	if concurrency > len(results) {
+		concurrency = len(results)
+	}
+
+	work := make(chan *scrumResult)
+	go func() {
+		defer close(work)
+		for _, r := range results {
+			work <- r
+		}
+	}()
+
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for r := range work {
+				r.obj, r.err = fn(r.scrumRequest)
+				close(r.ready)
+			}
+		}()
+	}
+
+	return results
+}
+
+// listDay returns every scrum entry for scrumDate.  A day without a scrum
+// directory, e.g. a day nobody has scrummed yet, has no entries.
+func listDay(ctx context.Context, store ScrumStore, scrumDate civilDate) ([]*ScrumEntry, error) {
+	entries, err := store.ListDay(ctx, scrumDate)
+	if err != nil && isScrumNotFoundError(err) {
+		return nil, nil
+	}
+
+	return entries, err
+}
+
+// listScrumDays lists every day using a bounded pool of workers and returns
+// the entries of each day in the same order as days.  Days without any scrums
+// have no entries.
+func listScrumDays(ctx context.Context, store ScrumStore, days []civilDate, concurrency int) ([][]*ScrumEntry, error) {
+	entries := make([][]*ScrumEntry, len(days))
+	errs := make([]error, len(days))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range days {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			entries[i], errs[i] = listDay(ctx, store, days[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to list scrums for %s", days[i].Format(dateInputFormat))
+		}
+	}
+
+	return entries, nil
+}
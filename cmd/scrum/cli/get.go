@@ -3,12 +3,9 @@ package cli
 import (
 	"bufio"
 	"bytes"
-	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
-	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -16,11 +13,9 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/gwydirsam/go-scrum/highlighter"
-	"github.com/joyent/triton-go/storage"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/ryanuber/columnize"
-	"github.com/sean-/conswriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"golang.org/x/crypto/ssh/terminal"
@@ -121,9 +116,9 @@ func init() {
 			key         = configKeyGetInputDate
 			longName    = "date"
 			shortName   = "D"
-			description = "Date for scrum"
+			description = "Date or range of dates for scrum (e.g. \"last friday\" or \"this week\")"
 		)
-		defaultValue := time.Now().Format(dateInputFormat)
+		defaultValue := dateToday
 
 		flags := getCmd.Flags()
 		flags.StringP(longName, shortName, defaultValue, description)
@@ -146,6 +141,21 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key         = configKeyGetOutput
+			longName    = "output"
+			shortName   = "o"
+			description = outputFormatDescription
+		)
+		defaultValue := _OutputFormatText.String()
+
+		flags := getCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key               = configKeyGetTomorrow
@@ -180,7 +190,10 @@ var getCmd = &cobra.Command{
 	Long:         `Get scrum information, either for yourself (or teammates)`,
 	SilenceUsage: true,
 	Example: `  $ scrum get                      # Get my scrum for today
-  $ scrum get -t -u other.username # Get other.username's scrum for tomorrow`,
+  $ scrum get -t -u other.username # Get other.username's scrum for tomorrow
+  $ scrum get -D "last friday"     # Get my scrum for last Friday
+  $ scrum get -a -D "this week"    # Get everyone's scrums for this week
+  $ scrum get -a -o json           # Get everyone's scrums for today as JSON`,
 	Args: cobra.NoArgs,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		if err := checkRequiredFlags(cmd.Flags()); err != nil {
@@ -197,25 +210,58 @@ var getCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		color.NoColor = !viper.GetBool(configKeyLogTermColor)
 
-		client, err := getScrumClient()
+		format, err := getOutputFormat(configKeyGetOutput)
 		if err != nil {
-			return errors.Wrap(err, "unable to create a new manta client")
+			return err
 		}
-		defer client.dumpMantaClientStats()
 
-		scrumDate, err := getDateInLocation(viper.GetString(configKeyGetInputDate))
+		store, err := getScrumStore()
+		if err != nil {
+			return errors.Wrap(err, "unable to create a new scrum store")
+		}
+		defer dumpStoreStats(store)
+
+		scrumDate, until, err := getDateRangeInLocation(viper.GetString(configKeyGetInputDate))
 		if err != nil {
 			return errors.Wrap(err, "unable to get scrum date")
 		}
 
+		username := interpolateUserEnvVar(viper.GetString(configKeyScrumUsername))
+
+		var days []civilDate
 		switch {
-		case viper.GetBool(configKeyGetTomorrow):
-			scrumDate = getNextWeekday(scrumDate)
-		case viper.GetBool(configKeyGetYesterday):
-			scrumDate = getPreviousWeekday(scrumDate)
+		case !scrumDate.Equal(until) && (viper.GetBool(configKeyGetTomorrow) || viper.GetBool(configKeyGetYesterday)):
+			return errors.New("tomorrow and yesterday can not be used with a range of dates")
+		case !scrumDate.Equal(until):
+			if days = getBusinessDays(scrumDate, until); len(days) == 0 {
+				return errors.Errorf("no business days between %s and %s", scrumDate, until)
+			}
+		case viper.GetBool(configKeyGetAll):
+			switch {
+			case viper.GetBool(configKeyGetTomorrow):
+				scrumDate = getNextWeekday(scrumDate)
+			case viper.GetBool(configKeyGetYesterday):
+				scrumDate = getPreviousWeekday(scrumDate)
+			}
+		case viper.GetBool(configKeyGetTomorrow), viper.GetBool(configKeyGetYesterday):
+			// Skip the days the user was out of the office.
+			cal, err := loadOOOCalendar()
+			if err != nil {
+				return err
+			}
+
+			scrumDate = getUserWeekday(store, cal, username, scrumDate, viper.GetBool(configKeyGetTomorrow))
+		}
+
+		if format != _OutputFormatText {
+			if len(days) == 0 {
+				days = []civilDate{scrumDate}
+			}
+
+			return getScrumRecords(cmd.OutOrStdout(), store, format, days, username, viper.GetBool(configKeyGetAll))
 		}
 
-		var w io.Writer = conswriter.GetTerminal()
+		var w io.Writer = cmd.OutOrStdout()
 
 		inputTokens := viper.GetStringMap(configKeyGetHighlight)
 
@@ -223,34 +269,9 @@ var getCmd = &cobra.Command{
 		case viper.IsSet(configKeyGetHighlight) && len(inputTokens) > 0:
 			toks := make([]*highlighter.TokenColor, 0, len(inputTokens))
 			for k, vRaw := range inputTokens {
-				tokenColor := &highlighter.TokenColor{
-					Color: &color.Color{},
-				}
-
-				// Extract meaning out of the highlight token
-				re := regexp.MustCompile(`^(.*?)(~([\d]*))?$`)
-				const tokenPos = 1
-				const fuzzyMatch = 2
-				const distanceTok = 3
-				md := re.FindStringSubmatch(k)
-				switch {
-				case md == nil || md[fuzzyMatch] == "":
-					tokenColor.Token = k
-				case md[distanceTok] == "":
-					tokenColor.Token = md[tokenPos]
-					tokenColor.Submatch = true
-				case md[distanceTok] != "":
-					tokenColor.Token = md[tokenPos]
-
-					dist, err := strconv.ParseInt(md[distanceTok], 0, 8)
-					if err != nil {
-						return errors.Wrap(err, "unable to parse distance")
-					}
-
-					tokenColor.Distance = int(dist)
-				default:
-					log.Warn().Str("token", k).Msgf("skipping %s.%q, input is not a valid token", configKeyGetHighlight, k)
-					continue
+				tokenColor, err := parseHighlightToken(k)
+				if err != nil {
+					return errors.Wrap(err, "unable to parse highlight token")
 				}
 
 				if _, ok := vRaw.(string); !ok {
@@ -293,124 +314,254 @@ var getCmd = &cobra.Command{
 		}
 
 		switch {
+		case len(days) > 0 && viper.GetBool(configKeyGetAll):
+			for _, date := range days {
+				writeDateHeading(w, date)
+				if err := getAllScrum(w, store, date); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		case len(days) > 0:
+			return getHistory(w, store, username, days)
 		case viper.GetBool(configKeyGetAll):
-			return getAllScrum(w, client, scrumDate)
+			return getAllScrum(w, store, scrumDate)
 		case !viper.GetBool(configKeyGetAll):
-			username := viper.GetString(configKeyScrumUsername)
-			username = interpolateUserEnvVar(username)
-			return getSingleScrum(w, client, scrumDate, username, false)
+			return getSingleScrum(w, store, scrumDate, username, false)
 		default:
 			return errors.New("unsupported get mode")
 		}
 	},
 }
 
-func getAllScrum(unbufOut io.Writer, c *scrumClient, scrumDate time.Time) error {
-	scrumPath := path.Join("stor", "scrum", scrumDate.Format(scrumDateLayout))
-
-	ctx, _ := context.WithTimeout(context.Background(), viper.GetDuration(configKeyMantaTimeout))
-	start := time.Now()
-	dirEnts, err := c.Dir().List(ctx, &storage.ListDirectoryInput{
-		DirectoryName: scrumPath,
-	})
-	elapsed := time.Now().Sub(start)
-	log.Debug().Str("path", scrumPath).Str("duration", elapsed.String()).Str("context", "pre-get all").Msg("ListDirectory")
-	c.Histogram.RecordValue(float64(elapsed) / float64(time.Second))
-	c.listCalls++
+// highlightTokenRE extracts the meaning of a highlight token: "keyword" is an
+// exact match, "keyword~" is a substring match, and "keyword~N" is a fuzzy
+// match within a Damerau–Levenshtein distance of N.
+var highlightTokenRE = regexp.MustCompile(`^(.*?)(~([\d]*))?$`)
+
+// parseHighlightToken parses a highlight token into a TokenColor without any
+// color attributes.
+func parseHighlightToken(k string) (*highlighter.TokenColor, error) {
+	tokenColor := &highlighter.TokenColor{
+		Color: &color.Color{},
+	}
+
+	const tokenPos = 1
+	const fuzzyMatch = 2
+	const distanceTok = 3
+	md := highlightTokenRE.FindStringSubmatch(k)
+	switch {
+	case md == nil || md[fuzzyMatch] == "":
+		tokenColor.Token = k
+	case md[distanceTok] == "":
+		tokenColor.Token = md[tokenPos]
+		tokenColor.Submatch = true
+	default:
+		tokenColor.Token = md[tokenPos]
+
+		dist, err := strconv.ParseInt(md[distanceTok], 0, 8)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse distance")
+		}
+
+		tokenColor.Distance = int(dist)
+	}
+
+	return tokenColor, nil
+}
+
+// getAllScrum fetches every user's scrum in parallel and renders each scrum in
+// directory order as soon as it and every scrum before it are ready.  A user
+// whose scrum can not be fetched is reported inline.
+func getAllScrum(unbufOut io.Writer, store ScrumStore, scrumDate civilDate) error {
+	ctx := cmdCtx
+	entries, err := listDay(ctx, store, scrumDate)
 	if err != nil {
-		return errors.Wrap(err, "unable to list manta directory")
+		return errors.Wrap(err, "unable to list scrum directory")
+	}
+
+	reqs := make([]scrumRequest, 0, len(entries))
+	for _, ent := range entries {
+		if v, found := usernameActionMap[ent.Name]; found && v == _Ignore {
+			continue
+		}
+
+		reqs = append(reqs, scrumRequest{date: scrumDate, user: ent.Name})
 	}
 
-	if dirEnts.ResultSetSize == 0 {
-		log.Error().Time("scrum-date", scrumDate).Msg("no users have scrummed for this day")
+	if len(reqs) == 0 {
+		log.Error().Str("scrum-date", scrumDate.String()).Msg("no users have scrummed for this day")
 		return nil
 	}
 
 	w := bufio.NewWriter(unbufOut)
 	defer w.Flush()
 
-	const defaultTerminalWidth = 80
-	terminalWidth, _, err := terminal.GetSize(int(os.Stdin.Fd()))
-	if err != nil {
-		log.Warn().Err(err).Msg("unable to get terminal size, using default")
-		terminalWidth = defaultTerminalWidth
+	horizontalSeparator := getHorizontalSeparator()
+
+	var numErrors int
+	for _, r := range fetchScrums(ctx, store, reqs, getConcurrency()) {
+		w.WriteString(horizontalSeparator)
+
+		obj, err := r.wait()
+		if err != nil {
+			log.Error().Err(err).Str("username", r.user).Msg("unable to get user's scrum")
+			fmt.Fprintf(w, "%s\n\nerror: unable to get scrum: %v\n", formatScrumHeader(r.user, time.Time{}, ScrumMetadata{}), err)
+			numErrors++
+		} else {
+			writeScrum(w, obj, true)
+		}
+
+		// Flush every entry in order to prevent tearing.
+		w.Flush()
 	}
 
-	horizontalSeparator := strings.Repeat("-", terminalWidth) + "\n"
+	if numErrors > 0 {
+		return errors.Errorf("unable to get %d of %d scrums", numErrors, len(reqs))
+	}
 
-	var firstError error
-	for _, ent := range dirEnts.Entries {
-		if v, found := usernameActionMap[ent.Name]; found && v == _Ignore {
+	return nil
+}
+
+// getScrumRecords fetches user's scrum, or every user's scrum if all is true,
+// for each day in parallel and writes them in a machine-readable format.  Days
+// without a scrum are skipped, unless only a single scrum was requested.
+func getScrumRecords(w io.Writer, store ScrumStore, format _OutputFormat, days []civilDate, user string, all bool) error {
+	var reqs []scrumRequest
+	for _, date := range days {
+		if !all {
+			reqs = append(reqs, scrumRequest{date: date, user: user})
 			continue
 		}
 
-		w.WriteString(horizontalSeparator)
+		entries, err := listDay(cmdCtx, store, date)
+		if err != nil {
+			return errors.Wrap(err, "unable to list scrum directory")
+		}
 
-		if err := getSingleScrum(w, c, scrumDate, ent.Name, true); err != nil {
-			log.Error().Err(err).Str("username", ent.Name).Msg("unable to get user's scrum")
-			if firstError == nil {
-				firstError = err
+		for _, ent := range entries {
+			if v, found := usernameActionMap[ent.Name]; found && v == _Ignore {
+				continue
 			}
+
+			reqs = append(reqs, scrumRequest{date: date, user: ent.Name})
+		}
+	}
+
+	records := make([]scrumRecord, 0, len(reqs))
+	var numErrors int
+	for _, r := range fetchScrums(cmdCtx, store, reqs, getConcurrency()) {
+		obj, err := r.wait()
+		switch {
+		case err != nil && !all && len(reqs) == 1:
+			return errors.Wrap(err, "unable to get scrum")
+		case err != nil && isScrumNotFoundError(err):
+			continue
+		case err != nil:
+			log.Error().Err(err).Str("username", r.user).Str("date", r.date.String()).Msg("unable to get user's scrum")
+			numErrors++
+		default:
+			records = append(records, newScrumRecord(r.date, obj, true))
 		}
+	}
 
-		// Paper over slow object fetching in Manta and flush every entry in order
-		// to prevent tearing.
-		//
-		// TODO(seanc@): fetch entries in parallel, pipeline all requests, or figure
-		// out how to do a multi-GET.
-		w.Flush()
+	if err := writeScrumRecords(w, format, records, true); err != nil {
+		return err
 	}
 
-	if firstError != nil {
-		return firstError
+	if numErrors > 0 {
+		return errors.Errorf("unable to get %d of %d scrums", numErrors, len(reqs))
 	}
 
 	return nil
 }
 
-func getSingleScrum(w io.Writer, c *scrumClient, scrumDate time.Time, user string, includeHeader bool) error {
-	objectPath := path.Join("stor", "scrum", scrumDate.Format(scrumDateLayout), user)
-
-	ctx, _ := context.WithTimeout(context.Background(), viper.GetDuration(configKeyMantaTimeout))
-	start := time.Now()
-	obj, err := c.Objects().Get(ctx, &storage.GetObjectInput{
-		ObjectPath: objectPath,
-	})
-	elapsed := time.Now().Sub(start)
-	log.Debug().Str("path", objectPath).Str("duration", elapsed.String()).Msg("GetObject")
-	c.Histogram.RecordValue(float64(elapsed) / float64(time.Second))
-	c.getCalls++
+func getSingleScrum(w io.Writer, store ScrumStore, scrumDate civilDate, user string, includeHeader bool) error {
+	obj, err := store.Get(cmdCtx, scrumDate, user)
 	if err != nil {
-		return errors.Wrap(err, "unable to get manta object")
+		return errors.Wrap(err, "unable to get scrum")
 	}
-	defer obj.ObjectReader.Close()
 
-	body, err := ioutil.ReadAll(obj.ObjectReader)
+	writeScrum(w, obj, includeHeader)
+
+	return nil
+}
+
+// writeDateHeading writes the date heading displayed above each day of a
+// range of dates.
+func writeDateHeading(w io.Writer, date civilDate) {
+	dateFmt := color.New(color.FgHiWhite, color.Bold).SprintFunc()
+	fmt.Fprintf(w, "%s\n", dateFmt(date.Format("2006-01-02 (Monday)")))
+}
+
+// getHorizontalSeparator returns a line the width of the terminal used to
+// separate scrums.
+func getHorizontalSeparator() string {
+	const defaultTerminalWidth = 80
+	terminalWidth, _, err := terminal.GetSize(int(os.Stdin.Fd()))
 	if err != nil {
-		return errors.Wrap(err, "unable to read manta object")
+		log.Warn().Err(err).Msg("unable to get terminal size, using default")
+		terminalWidth = defaultTerminalWidth
 	}
 
-	if includeHeader {
-		keyFmt := color.New(color.FgHiWhite, color.Bold).SprintFunc()
-		userFmt := color.New(color.FgHiWhite, color.Underline).SprintFunc()
-		mtimeFmt := color.New().SprintFunc()
+	return strings.Repeat("-", terminalWidth) + "\n"
+}
 
-		var mtime time.Time
-		if viper.GetBool(configKeyUseUTC) {
-			mtime = obj.LastModified.UTC()
-		} else {
-			mtime = obj.LastModified.Local()
-		}
+// formatScrumHeader formats the user, mtime and metadata header displayed
+// above a scrum.  A zero mtime and empty metadata fields are omitted.
+func formatScrumHeader(user string, mtime time.Time, md ScrumMetadata) string {
+	return columnize.SimpleFormat(scrumHeaderRows(user, mtime, md))
+}
+
+// scrumHeaderRows returns the rows of a scrum header in the "key | value"
+// format used by columnize.
+func scrumHeaderRows(user string, mtime time.Time, md ScrumMetadata) []string {
+	keyFmt := color.New(color.FgHiWhite, color.Bold).SprintFunc()
+	userFmt := color.New(color.FgHiWhite, color.Underline).SprintFunc()
+	mtimeFmt := color.New().SprintFunc()
+	statusFmt := color.New(color.FgHiYellow).SprintFunc()
+
+	output := []string{
+		fmt.Sprintf("%s | %s", keyFmt("user"), userFmt(user)),
+	}
+
+	if !mtime.IsZero() {
+		mtime = displayTime(mtime)
+
+		output = append(output, fmt.Sprintf("%s | %s", keyFmt("mtime"), mtimeFmt(mtime.Format(mtimeFormatTZ))))
+	}
 
-		output := []string{
-			fmt.Sprintf("%s | %s", keyFmt("user"), userFmt(user)),
-			fmt.Sprintf("%s | %s", keyFmt("mtime"), mtimeFmt(mtime.Format(mtimeFormatTZ))),
+	if md.Status != "" && md.Status != scrumStatusNormal {
+		status := md.Status
+		if !md.EndDate.IsZero() {
+			status += " until " + md.EndDate.Format(scrumMetadataDateLayout)
 		}
-		w.Write([]byte(columnize.SimpleFormat(output) + "\n\n"))
+
+		output = append(output, fmt.Sprintf("%s | %s", keyFmt("status"), statusFmt(status)))
 	}
 
-	w.Write(bytes.TrimSpace(body))
-	w.Write([]byte("\n"))
+	if md.Author != "" && md.Author != user {
+		output = append(output, fmt.Sprintf("%s | %s", keyFmt("author"), md.Author))
+	}
 
-	return nil
+	if md.SourceFile != "" {
+		output = append(output, fmt.Sprintf("%s | %s", keyFmt("source"), md.SourceFile))
+	}
+
+	if md.ClientVersion != "" {
+		output = append(output, fmt.Sprintf("%s | %s", keyFmt("client"), md.ClientVersion))
+	}
+
+	return output
+}
+
+// writeScrum writes the body of a scrum, optionally preceded by a header.
+func writeScrum(w io.Writer, obj *ScrumObject, includeHeader bool) {
+	if includeHeader {
+		w.Write([]byte(formatScrumHeader(obj.Name, obj.ModifiedTime, obj.Metadata) + "\n\n"))
+	}
+
+	w.Write(bytes.TrimSpace(obj.Body))
+	w.Write([]byte("\n"))
 }
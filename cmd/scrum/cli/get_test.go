@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetSingle(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("  did things  \n\n"))
+
+	out := env.mustRun("get", "-u", "alice")
+	if got, want := out, "did things\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestGetMissing(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	_, err := env.run("get", "-u", "alice")
+	if err == nil {
+		t.Fatalf("get of a missing scrum succeeded")
+	}
+
+	if !isScrumNotFoundError(err) {
+		t.Errorf("error = %v, want a not found error", err)
+	}
+}
+
+func TestGetHighlightIsReset(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("did things\n"))
+
+	highlight := func() []string {
+		values, err := getCmd.Flags().GetStringArray("highlight")
+		if err != nil {
+			t.Fatalf("unable to get highlight flag: %v", err)
+		}
+
+		return values
+	}
+
+	env.mustRun("get", "-u", "alice", "-H", "things")
+	env.mustRun("get", "-u", "alice")
+
+	if got := highlight(); len(got) != 0 {
+		t.Errorf("highlight = %q, want none", got)
+	}
+
+	env.mustRun("get", "-u", "alice", "-H", "did")
+	if got, want := highlight(), []string{"did"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("highlight = %q, want %q", got, want)
+	}
+}
+
+func TestGetYesterdaySkipsWeekend(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/09/alice", []byte("friday\n"))
+
+	out := env.mustRun("get", "-u", "alice", "-y")
+	if got, want := out, "friday\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/bob", []byte("bob's scrum\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("alice's scrum\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/rollup", []byte("rollup\n"))
+
+	out := env.mustRun("get", "-a")
+
+	alice := strings.Index(out, "alice's scrum")
+	bob := strings.Index(out, "bob's scrum")
+	switch {
+	case alice == -1 || bob == -1:
+		t.Fatalf("output is missing scrums:\n%s", out)
+	case alice > bob:
+		t.Errorf("scrums are not in directory order:\n%s", out)
+	}
+
+	if strings.Contains(out, "rollup") {
+		t.Errorf("output includes the ignored rollup object:\n%s", out)
+	}
+
+	if !strings.Contains(out, "2018-03-12 10:00:00 UTC") {
+		t.Errorf("output is missing the mtime header:\n%s", out)
+	}
+}
+
+func TestGetAllReportsErrorsInline(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	for _, user := range []string{"alice", "bob", "carol"} {
+		env.manta.PutObject("stor/scrum/2018/03/12/"+user, []byte(user+"'s scrum\n"))
+	}
+	env.manta.FailRequests("stor/scrum/2018/03/12/bob", -1, http.StatusForbidden, "AuthorizationFailed")
+
+	for _, concurrency := range []string{"1", "8"} {
+		out, err := env.run("get", "-a", "-j", concurrency)
+		if err == nil {
+			t.Errorf("-j %s: get -a with a failed scrum succeeded", concurrency)
+		}
+
+		alice := strings.Index(out, "alice's scrum")
+		bob := strings.Index(out, "error: unable to get scrum")
+		carol := strings.Index(out, "carol's scrum")
+		if alice == -1 || bob == -1 || carol == -1 || !(alice < bob && bob < carol) {
+			t.Errorf("-j %s: scrums are missing or out of order:\n%s", concurrency, out)
+		}
+	}
+}
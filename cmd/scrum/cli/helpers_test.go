@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gwydirsam/go-scrum/cmd/scrum/internal/mantatest"
+	"github.com/joyent/triton-go/authentication"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const testAccount = "Joyent_Dev"
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	os.Exit(m.Run())
+}
+
+// testEnv is a scrum environment backed by a fake Manta server and a fixed
+// clock.
+type testEnv struct {
+	t     *testing.T
+	manta *mantatest.Server
+	dir   string
+	now   time.Time
+}
+
+// newTestEnv starts a fake Manta server and points the scrum configuration at
+// it.  The clock starts at 2018-03-12 (a Monday) 10:00 UTC.
+func newTestEnv(t *testing.T) *testEnv {
+	dir, err := ioutil.TempDir("", "scrum-test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+
+	env := &testEnv{
+		t:   t,
+		dir: dir,
+		now: time.Date(2018, time.March, 12, 10, 0, 0, 0, time.UTC),
+	}
+
+	keyFile := filepath.Join(dir, "id_rsa")
+	if err := ioutil.WriteFile(keyFile, authentication.Dummy.PrivateKey, 0600); err != nil {
+		t.Fatalf("unable to write private key: %v", err)
+	}
+
+	env.manta, err = mantatest.New(mantatest.Config{
+		Account:   testAccount,
+		PublicKey: authentication.Dummy.PublicKey,
+		Now:       func() time.Time { return env.now },
+	})
+	if err != nil {
+		t.Fatalf("unable to start fake manta: %v", err)
+	}
+
+	env.manta.PutDirectory("stor/scrum")
+
+	timeNow = func() time.Time { return env.now }
+
+	viper.Set(configKeyMantaURL, env.manta.URL)
+	viper.Set(configKeyMantaAccount, testAccount)
+	viper.Set(configKeyScrumAccount, testAccount)
+	viper.Set(configKeyMantaKeyID, authentication.Dummy.Fingerprint)
+	viper.Set(configKeyMantaKeyMaterial, keyFile)
+	viper.Set(configKeyStorageBackend, storageBackendManta)
+	viper.Set(configKeyUseUTC, true)
+	viper.Set(configKeyLogStats, false)
+	viper.Set(configKeyLogTermColor, false)
+
+	return env
+}
+
+func (env *testEnv) Close() {
+	env.manta.Close()
+	os.RemoveAll(env.dir)
+	timeNow = time.Now
+}
+
+// writeFile writes a scrum input file and returns its name.
+func (env *testEnv) writeFile(name, body string) string {
+	filename := filepath.Join(env.dir, name)
+	if err := ioutil.WriteFile(filename, []byte(body), 0644); err != nil {
+		env.t.Fatalf("unable to write %q: %v", filename, err)
+	}
+
+	return filename
+}
+
+// run executes the scrum command with args and returns its output.
+func (env *testEnv) run(args ...string) (string, error) {
+	resetFlags(rootCmd)
+
+	var out bytes.Buffer
+	rootCmd.SetOutput(&out)
+	rootCmd.SetArgs(args)
+	err := rootCmd.Execute()
+
+	return out.String(), err
+}
+
+// mustRun executes the scrum command and fails the test on error.
+func (env *testEnv) mustRun(args ...string) string {
+	out, err := env.run(args...)
+	if err != nil {
+		env.t.Fatalf("scrum %v: %v", args, err)
+	}
+
+	return out
+}
+
+// resetFlags restores every flag changed by a previous run to its default
+// value.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+
+		switch f.Value.Type() {
+		case "stringArray", "stringSlice":
+			// Setting a slice flag that was changed appends to it, so its value
+			// is replaced by a new one holding the default.
+			f.Value = newSliceFlagValue(f)
+		default:
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+// newSliceFlagValue returns a new value for the slice flag f holding its
+// default value.
+func newSliceFlagValue(f *pflag.Flag) pflag.Value {
+	var defaultValue []string
+	if raw := strings.TrimSuffix(strings.TrimPrefix(f.DefValue, "["), "]"); raw != "" {
+		defaultValue, _ = csv.NewReader(strings.NewReader(raw)).Read()
+	}
+
+	flags := pflag.NewFlagSet(f.Name, pflag.ContinueOnError)
+	if f.Value.Type() == "stringSlice" {
+		flags.StringSlice(f.Name, defaultValue, f.Usage)
+	} else {
+		flags.StringArray(f.Name, defaultValue, f.Usage)
+	}
+
+	return flags.Lookup(f.Name).Value
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanuber/columnize"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultHistoryDays is the number of days before --until shown when --since
+// is not specified.
+const defaultHistoryDays = 14
+
+var historyCmd = &cobra.Command{
+	Use:          "history",
+	SuggestFor:   []string{"log"},
+	Short:        "Get a user's scrums over a range of dates",
+	Long:         `Get a user's scrums for every business day in a range of dates`,
+	SilenceUsage: true,
+	Example: `  $ scrum history                                      # My scrums for the last two weeks
+  $ scrum history -u other.username --since 2018-03-01 # other.username's scrums since March 1st`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkRequiredFlags(cmd.Flags()); err != nil {
+			return errors.Wrap(err, "required flag missing")
+		}
+
+		return nil
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		color.NoColor = !viper.GetBool(configKeyLogTermColor)
+
+		since, until, err := getDateRange(configKeyHistorySince, configKeyHistoryUntil, defaultHistoryDays)
+		if err != nil {
+			return err
+		}
+
+		store, err := getScrumStore()
+		if err != nil {
+			return errors.Wrap(err, "unable to create a new scrum store")
+		}
+		defer dumpStoreStats(store)
+
+		username := interpolateUserEnvVar(viper.GetString(configKeyScrumUsername))
+
+		return getHistory(cmd.OutOrStdout(), store, username, getBusinessDays(since, until))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	{
+		const (
+			key          = configKeyHistorySince
+			longName     = "since"
+			shortName    = ""
+			defaultValue = ""
+			description  = "First date of the history (defaults to two weeks before --until)"
+		)
+
+		flags := historyCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key         = configKeyHistoryUntil
+			longName    = "until"
+			shortName   = ""
+			description = "Last date of the history"
+		)
+		defaultValue := dateToday
+
+		flags := historyCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+}
+
+// getDateRange parses the since and until dates stored in the given
+// configuration keys.  An empty since date defaults to defaultDays before the
+// until date.
+func getDateRange(sinceKey, untilKey string, defaultDays int) (since, until civilDate, err error) {
+	until, err = getDateInLocation(viper.GetString(untilKey))
+	if err != nil {
+		return since, until, errors.Wrap(err, "unable to parse until date")
+	}
+
+	since = until.AddDate(0, 0, -defaultDays)
+	if sinceStr := viper.GetString(sinceKey); sinceStr != "" {
+		if since, err = getDateInLocation(sinceStr); err != nil {
+			return since, until, errors.Wrap(err, "unable to parse since date")
+		}
+	}
+
+	if since.After(until) {
+		return since, until, errors.Errorf("since date (%s) is after the until date (%s)", since.Format(dateInputFormat), until.Format(dateInputFormat))
+	}
+
+	return since, until, nil
+}
+
+// getBusinessDays returns every business day between since and until,
+// inclusive.
+func getBusinessDays(since, until civilDate) []civilDate {
+	var days []civilDate
+	for date := since; !date.After(until); date = date.AddDate(0, 0, 1) {
+		if isBusinessDay(date) {
+			days = append(days, date)
+		}
+	}
+
+	return days
+}
+
+// getHistory fetches user's scrum for each day in parallel and renders them in
+// order.  Days without a scrum are marked as missing.
+func getHistory(unbufOut io.Writer, store ScrumStore, user string, days []civilDate) error {
+	if len(days) == 0 {
+		log.Warn().Msg("no business days in the requested range")
+		return nil
+	}
+
+	reqs := make([]scrumRequest, 0, len(days))
+	for _, date := range days {
+		reqs = append(reqs, scrumRequest{date: date, user: user})
+	}
+
+	w := bufio.NewWriter(unbufOut)
+	defer w.Flush()
+
+	horizontalSeparator := getHorizontalSeparator()
+	keyFmt := color.New(color.FgHiWhite, color.Bold).SprintFunc()
+	missingFmt := color.New(color.FgHiBlack).SprintFunc()
+
+	var numErrors int
+	for _, r := range fetchScrums(cmdCtx, store, reqs, getConcurrency()) {
+		w.WriteString(horizontalSeparator)
+
+		dateRow := fmt.Sprintf("%s | %s", keyFmt("date"), r.date.Format("2006-01-02 (Monday)"))
+
+		obj, err := r.wait()
+		switch {
+		case err != nil && isScrumNotFoundError(err):
+			fmt.Fprintf(w, "%s\n\n%s\n", columnize.SimpleFormat([]string{dateRow}), missingFmt("(no scrum)"))
+		case err != nil:
+			log.Error().Err(err).Str("username", user).Str("date", r.date.Format(dateInputFormat)).Msg("unable to get user's scrum")
+			fmt.Fprintf(w, "%s\n\nerror: unable to get scrum: %v\n", columnize.SimpleFormat([]string{dateRow}), err)
+			numErrors++
+		default:
+			// The user is the same for every day, so the header's user row is
+			// replaced with the date.
+			rows := append([]string{dateRow}, scrumHeaderRows(obj.Name, obj.ModifiedTime, obj.Metadata)[1:]...)
+			fmt.Fprintf(w, "%s\n\n", columnize.SimpleFormat(rows))
+			writeScrum(w, obj, false)
+		}
+
+		// Flush every entry in order to prevent tearing.
+		w.Flush()
+	}
+
+	if numErrors > 0 {
+		return errors.Errorf("unable to get %d of %d scrums", numErrors, len(reqs))
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/05/alice", []byte("monday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/07/alice", []byte("wednesday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("next monday\n"))
+
+	out := env.mustRun("history", "-u", "alice", "--since", "2018-03-05", "--until", "2018-03-12")
+
+	var last int
+	for _, want := range []string{
+		"2018-03-05 (Monday)", "monday",
+		"2018-03-06 (Tuesday)", "(no scrum)",
+		"2018-03-07 (Wednesday)", "wednesday",
+		"2018-03-08 (Thursday)", "(no scrum)",
+		"2018-03-09 (Friday)", "(no scrum)",
+		"2018-03-12 (Monday)", "next monday",
+	} {
+		i := strings.Index(out[last:], want)
+		if i == -1 {
+			t.Fatalf("output is missing %q after offset %d:\n%s", want, last, out)
+		}
+		last += i + len(want)
+	}
+
+	for _, weekend := range []string{"2018-03-10", "2018-03-11"} {
+		if strings.Contains(out, weekend) {
+			t.Errorf("output includes the weekend day %s:\n%s", weekend, out)
+		}
+	}
+}
+
+func TestHistorySkipsHolidays(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	out := env.mustRun("history", "-u", "alice", "--since", "2018-04-12", "--until", "2018-04-16")
+	if strings.Contains(out, "2018-04-13") {
+		t.Errorf("output includes a holiday:\n%s", out)
+	}
+
+	if got, want := strings.Count(out, "(no scrum)"), 2; got != want {
+		t.Errorf("missing days = %d, want %d:\n%s", got, want, out)
+	}
+}
+
+func TestHistoryReportsErrors(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("monday\n"))
+	env.manta.FailRequests("stor/scrum/2018/03/12/alice", -1, http.StatusForbidden, "AuthorizationFailed")
+
+	out, err := env.run("history", "-u", "alice", "--since", "2018-03-09", "--until", "2018-03-12")
+	if err == nil {
+		t.Errorf("history with a failed scrum succeeded")
+	}
+
+	if !strings.Contains(out, "error: unable to get scrum") {
+		t.Errorf("output does not report the error:\n%s", out)
+	}
+}
+
+func TestHistoryRejectsReversedRange(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	if _, err := env.run("history", "--since", "2018-03-12", "--until", "2018-03-05"); err == nil {
+		t.Errorf("history with since after until succeeded")
+	}
+}
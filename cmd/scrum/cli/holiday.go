@@ -1,42 +1,22 @@
 package cli
 
 import (
-	"fmt"
+	"os"
+	"regexp"
 	"strings"
 	"sync"
-	"text/scanner"
-	"time"
-	"unicode"
 
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
+// joyentHolidays are the company holidays that do not follow a rule.  They are
+// the default of the holidays config key, which adds to or overrides the
+// holidays computed from holidayRules.
 var joyentHolidays = map[string]string{
-	"2018-01-01": `ca,uk,us: New Year's Day`,
-	"2018-01-15": `us: Martin Luther King Day`,
-	"2018-02-12": `ca: Family Day (BC)`,
-	"2018-02-19": `ca,us: ca:"Family Day (AB, MB, ON, PE, SK)" us:"President's Day"`,
-	"2018-03-30": `ca,uk: Good Friday`,
-	"2018-04-02": `uk: Easter Monday`,
 	"2018-04-13": `us: Wellbeing Day`,
-	"2018-05-07": `uk: Early May bank holiday`,
-	"2018-05-21": `ca: Victoria Day`,
-	"2018-05-28": `uk: Spring bank holiday`,
-	"2018-05-29": `us: Memorial Day`,
-	"2018-06-25": `ca: National Holiday (QC)`,
-	"2018-07-02": `ca: Canada Day, observed`,
-	"2018-07-04": `us: Independence Day`,
-	"2018-08-06": `ca: Civic Day (AB, BC, ON, NS, MB)`,
-	"2018-08-27": `uk: Summer bank holiday`,
-	"2018-09-03": `ca, us: Labor Day`,
-	"2018-10-08": `ca: Thanksgiving Day`,
-	"2018-11-12": `ca: Remembrance Day, observed (AB, BC, NS)`,
-	"2018-11-22": `us: Thanksgiving Day`,
-	"2018-11-23": `us: Day After Thanksgiving`,
-	"2018-12-24": `us: Christmas Eve`,
-	"2018-12-25": `ca,uk,us: Christmas Day`,
-	"2018-12-26": `ca,uk: Boxing Day`,
 }
 
 // _Holiday is a statically typed string with implied meaning in the structure of the string itself.
@@ -44,115 +24,258 @@ var joyentHolidays = map[string]string{
 // examples:
 // "country: holiday name"
 // "country1, country2: holiday name"
+// "country-region: holiday name"
 // `country1, country2: country1:"country1 holiday name" country2:"country 2 holiday name"`
 type _Holiday string
 
-// getCountries extracts the countries from a given _Holiday
-func (h _Holiday) getCountries() []string {
-	fieldsFunc := func(c rune) bool {
-		return !unicode.IsLetter(c)
+// holidayCountryRE matches a country in a _Holiday's list of countries,
+// optionally followed by a region, e.g. "ca-qc".
+var holidayCountryRE = regexp.MustCompile(`^[a-z]+(-[a-z0-9]+)?$`)
+
+// holidayExplicitRE matches the start of a _Holiday with a name per country,
+// e.g. `ca:"Family Day"`.
+var holidayExplicitRE = regexp.MustCompile(`^[a-z]+(-[a-z0-9]+)?\s*:\s*"`)
+
+// parse returns the countries that observe the holiday and the holiday's name
+// in each of them.  An error describes the first problem with the format.
+func (h _Holiday) parse() ([]string, map[string]string, error) {
+	parts := strings.SplitN(string(h), ":", 2)
+	if len(parts) != 2 {
+		return nil, nil, errors.New(`missing ":" after the countries, format must be: "country: holiday name"`)
 	}
 
-	inObservance := strings.Split(string(h), ":")
-	if len(inObservance) < 2 {
-		panic(fmt.Sprintf(`invalid holiday (%+q): format must be: "country: holday name"`, string(h)))
+	var countries []string
+	names := make(map[string]string)
+	for _, country := range strings.Split(parts[0], ",") {
+		country = strings.ToLower(strings.TrimSpace(country))
+		switch {
+		case country == "":
+			return nil, nil, errors.Errorf("empty country in country list %q", parts[0])
+		case !holidayCountryRE.MatchString(country):
+			return nil, nil, errors.Errorf("invalid country %q in country list %q", country, parts[0])
+		}
+
+		if _, found := names[country]; !found {
+			countries = append(countries, country)
+			names[country] = ""
+		}
 	}
 
-	countries := strings.FieldsFunc(inObservance[0], fieldsFunc)
+	rest := strings.TrimSpace(parts[1])
+	if rest == "" {
+		return nil, nil, errors.New("missing holiday name")
+	}
 
-	return countries
-}
+	if len(countries) == 1 || !holidayExplicitRE.MatchString(rest) {
+		for _, country := range countries {
+			names[country] = rest
+		}
 
-// getCountryHoliday returns the holiday name for the given country.
-func (h _Holiday) getCountryHoliday(countryName string) (string, error) {
-	countries := h.getCountries()
-	numCountries := len(countries)
+		return countries, names, nil
+	}
+
+	// Scanning for country:"name" pairs.
+	for rest != "" {
+		colon := strings.Index(rest, ":")
+		if colon == -1 {
+			return nil, nil, errors.Errorf(`missing ":" after %q, format must be: country:"holiday name"`, rest)
+		}
+
+		country := strings.ToLower(strings.TrimSpace(rest[:colon]))
+		if _, found := names[country]; !found {
+			return nil, nil, errors.Errorf("country %q is not in the country list %q", country, parts[0])
+		}
+
+		rest = strings.TrimSpace(rest[colon+1:])
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, nil, errors.Errorf(`missing opening quote for the name of %s's holiday`, country)
+		}
 
-	holiday := strings.SplitN(string(h), ":", 2)
-	if len(holiday) == 1 {
-		return "", fmt.Errorf("unable to extract country from holiday: %q", h)
+		end := strings.Index(rest[1:], `"`)
+		if end == -1 {
+			return nil, nil, errors.Errorf(`missing closing quote for the name of %s's holiday`, country)
+		}
+
+		if names[country] = strings.TrimSpace(rest[1 : end+1]); names[country] == "" {
+			return nil, nil, errors.Errorf("empty name for %s's holiday", country)
+		}
+
+		rest = strings.TrimSpace(rest[end+2:])
 	}
 
-	if numCountries == 1 {
-		return holiday[1], nil
+	for _, country := range countries {
+		if names[country] == "" {
+			return nil, nil, errors.Errorf("missing name for %s's holiday", country)
+		}
 	}
 
-	// Create a scanner to tokenize the input.
-	var s scanner.Scanner
-	s.Init(strings.NewReader(holiday[1]))
-	s.Error = func(s *scanner.Scanner, msg string) {
-		log.Warn().Str("holiday", string(h)).Int("character", s.Position.Column).Str("msg", msg).Msg("error scanning input")
+	return countries, names, nil
+}
+
+// holidayCache holds the holidays of every year looked up so far.
+var holidayCache = struct {
+	sync.Mutex
+	years map[int]holidayCalendar
+
+	// calendars are the events of every holiday calendar file, keyed by
+	// filename.
+	calendars map[string][]*icsEvent
+}{
+	years:     make(map[int]holidayCalendar),
+	calendars: make(map[string][]*icsEvent),
+}
+
+// getHolidays returns the holidays of date's year keyed by their date and then
+// by country or region.  The holidays are computed from holidayRules and the
+// holidays config key, which adds to or overrides the holidays of the
+// countries it lists on a given date or imports them from an iCalendar file.
+// Holidays are civil dates, so every caller sees the same holidays on the same
+// day regardless of their timezone.
+func getHolidays(date civilDate) holidayCalendar {
+	holidayCache.Lock()
+	defer holidayCache.Unlock()
+
+	year := date.Year()
+	if cal, found := holidayCache.years[year]; found {
+		return cal
 	}
-	s.Filename = "holiday"
-	s.IsIdentRune = func(ch rune, i int) bool {
-		return unicode.IsLetter(ch) && i >= 0
+
+	// A holiday observed on the previous weekday can fall in the year before,
+	// e.g. New Year's Day on a Saturday.
+	cal := make(holidayCalendar)
+	for _, y := range []int{year, year + 1} {
+		for date, countries := range computeHolidays(y) {
+			if date.Year() == year {
+				cal[date] = countries
+			}
+		}
+	}
+
+	// Calendar files are applied before dated entries so that a dated entry
+	// can override a holiday imported from a calendar.
+	holidays := viper.GetStringMapString(configKeyHolidays)
+	for key, holiday := range holidays {
+		h := _Holiday(holiday)
+		filename, found := h.calendarFile()
+		if !found {
+			continue
+		}
+
+		countries, _, err := h.parse()
+		if err != nil {
+			log.Warn().Err(err).Str("holidays", key).Str("holiday", holiday).Msg("invalid holiday")
+			continue
+		}
+
+		for _, ev := range loadHolidayCalendar(filename) {
+			for day := ev.Start; !day.After(ev.End); day = day.AddDate(0, 0, 1) {
+				if day.Year() != year {
+					continue
+				}
+
+				for _, country := range countries {
+					cal.add(day, country, ev.Summary)
+				}
+			}
+		}
+
+		log.Debug().Str("holidays", key).Str("file", filename).Msg("imported holiday calendar")
 	}
-	s.Mode = scanner.ScanIdents
-	for tok := s.Scan(); tok != scanner.EOF; tok = s.Scan() {
-		// scanning for country:"name"
-		country := s.TokenText()
 
-		if s.Peek() != ':' {
-			// malformed explicit format, fall back to returning the whole string
-			break
+	for dateStr, holiday := range holidays {
+		h := _Holiday(holiday)
+		if _, found := h.calendarFile(); found {
+			continue
 		}
 
-		tok = s.Scan() // Eat the :
-		if tok == scanner.EOF {
-			return holiday[1], fmt.Errorf("malformed holiday country separator: +q", h)
+		date, err := parseCivilDate(dateInputFormat, dateStr)
+		if err != nil {
+			log.Warn().Err(err).Str("date", dateStr).Str("holiday", holiday).Msg("unable to parse holiday date")
+			continue
 		}
 
-		s.Mode = scanner.ScanStrings
-		tok = s.Scan()
-		if tok == scanner.EOF {
-			return holiday[1], fmt.Errorf("malformed holiday string: +q", h)
+		if date.Year() != year {
+			continue
 		}
 
-		if countryName == country {
-			// We found our country's holiday
-			return strings.Trim(s.TokenText(), `"`), nil
+		countries, names, err := h.parse()
+		if err != nil {
+			log.Warn().Err(err).Str("date", dateStr).Str("holiday", holiday).Msg("invalid holiday")
+			continue
 		}
 
-		// Back to scanning characters
-		s.Mode = scanner.ScanIdents
+		for _, country := range countries {
+			cal.add(date, country, names[country])
+		}
 	}
 
-	return strings.TrimSpace(holiday[1]), nil
+	holidayCache.years[year] = cal
+
+	return cal
 }
 
-var cachedHolidayMap map[time.Time]_Holiday
-var holidayOnce sync.Once
+// resetHolidayCache forgets every computed holiday, e.g. after the holidays
+// config key changes.
+func resetHolidayCache() {
+	holidayCache.Lock()
+	defer holidayCache.Unlock()
 
-// getHolidays reads a list of holidays and returns it as a map to the caller.
-//
-// NOTE(seanc@): The times are local to the caller of this utility.  This is a
-// bit sketchy in terms of correctness, but good enough as long as the dates
-// stored in the map match the dates from the perspective of the caller.
-// Comparison across timezones?  Not so much.
-//
-// TODO(seanc@): build an inverted index and index the intervals or time spans
-// and search the index for a matching holiday in a given country.
-func getHolidays() map[time.Time]_Holiday {
-	buildHolidayCache := func() {
-		holidays := viper.GetStringMapString(configKeyHolidays)
-		holidayMap := make(map[time.Time]_Holiday, len(holidays))
-
-		// Always parse holidays local to their timezone.  Create a cache of countries
-		// and their location.
-
-		for dateStr, holiday := range holidays {
-			date, err := getDateInLocation(dateStr)
-			if err != nil {
-				log.Warn().Err(err).Str("date", dateStr).Str("holiday", holiday).Msg("unable to parse holiday date")
-				continue
-			}
-			holidayMap[date] = _Holiday(holiday)
-		}
+	holidayCache.years = make(map[int]holidayCalendar)
+	holidayCache.calendars = make(map[string][]*icsEvent)
+}
 
-		cachedHolidayMap = holidayMap
+// calendarFile returns the iCalendar file a holiday refers to instead of
+// naming a holiday, e.g. "ca,uk: ~/holidays.ics".  Every event in the file is
+// a holiday of the listed countries.
+func (h _Holiday) calendarFile() (string, bool) {
+	parts := strings.SplitN(string(h), ":", 2)
+	if len(parts) != 2 {
+		return "", false
 	}
 
-	holidayOnce.Do(buildHolidayCache)
+	filename := strings.TrimSpace(parts[1])
+	if !strings.HasSuffix(strings.ToLower(filename), ".ics") {
+		return "", false
+	}
+
+	return filename, true
+}
+
+// loadHolidayCalendar returns the events of a holiday calendar file.  A file
+// that can not be read is logged and treated as empty.  The caller must hold
+// holidayCache's lock.
+func loadHolidayCalendar(rawFilename string) []*icsEvent {
+	if events, found := holidayCache.calendars[rawFilename]; found {
+		return events
+	}
+
+	events, err := readHolidayCalendar(rawFilename)
+	if err != nil {
+		log.Warn().Err(err).Str("file", rawFilename).Msg("unable to load holiday calendar")
+	}
+
+	holidayCache.calendars[rawFilename] = events
+
+	return events
+}
+
+// readHolidayCalendar parses a holiday calendar file.
+func readHolidayCalendar(rawFilename string) ([]*icsEvent, error) {
+	filename, err := homedir.Expand(rawFilename)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find a user's home directory")
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open holiday calendar")
+	}
+	defer f.Close()
+
+	loc, err := getLocation()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get location")
+	}
 
-	return cachedHolidayMap
+	return parseICS(f, loc)
 }
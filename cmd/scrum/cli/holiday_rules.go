@@ -0,0 +1,333 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// holidayObservance is the day a holiday is observed when it falls on a
+// weekend or on a day that is already a holiday.
+type holidayObservance int
+
+const (
+	// observeOnDay holidays are only observed on the day itself.
+	observeOnDay holidayObservance = iota
+
+	// observeNextWeekday holidays are observed on the following Monday when
+	// they fall on a weekend.
+	observeNextWeekday
+
+	// observeNearestWeekday holidays are observed on the Friday before when
+	// they fall on a Saturday and on the Monday after when they fall on a
+	// Sunday.
+	observeNearestWeekday
+
+	// observePreviousWeekday holidays are observed on the Friday before when
+	// they fall on a weekend.
+	observePreviousWeekday
+)
+
+// holidayDateFunc returns the date of a holiday in year.
+type holidayDateFunc func(year int) civilDate
+
+// holidayRule computes the date of a holiday for any year.
+type holidayRule struct {
+	// observers is the comma separated list of countries that observe the
+	// holiday.  A region of a country, e.g. "ca-qc", observes the holidays of
+	// its country and its own.
+	observers string
+
+	name       string
+	date       holidayDateFunc
+	observance holidayObservance
+
+	// since and until are the first and last year the rule applies to.  Zero
+	// is unbounded.
+	since, until int
+}
+
+// holidayRules are the holidays of every country, in the order they occur in
+// a year.  A holiday observed on a substitute day is moved past the holidays
+// before it.
+var holidayRules = []holidayRule{
+	{observers: "us", name: "New Year's Day", date: fixedDate(time.January, 1), observance: observeNearestWeekday},
+	{observers: "ca,uk", name: "New Year's Day", date: fixedDate(time.January, 1), observance: observeNextWeekday},
+	{observers: "us", name: "Martin Luther King Day", date: nthWeekday(3, time.Monday, time.January)},
+	{observers: "ca-bc", name: "Family Day (BC)", date: nthWeekday(2, time.Monday, time.February), since: 2013, until: 2018},
+	{observers: "ca-bc", name: "Family Day (BC)", date: nthWeekday(3, time.Monday, time.February), since: 2019},
+	{observers: "ca-ab,ca-mb,ca-on,ca-pe,ca-sk", name: "Family Day (AB, MB, ON, PE, SK)", date: nthWeekday(3, time.Monday, time.February)},
+	{observers: "us", name: "President's Day", date: nthWeekday(3, time.Monday, time.February)},
+	{observers: "ca,uk", name: "Good Friday", date: easterOffset(-2)},
+	{observers: "uk", name: "Easter Monday", date: easterOffset(1)},
+	{observers: "uk", name: "Early May bank holiday", date: nthWeekday(1, time.Monday, time.May)},
+	{observers: "ca", name: "Victoria Day", date: weekdayBefore(time.Monday, time.May, 25)},
+	{observers: "uk", name: "Spring bank holiday", date: nthWeekday(-1, time.Monday, time.May)},
+	{observers: "us", name: "Memorial Day", date: nthWeekday(-1, time.Monday, time.May)},
+	{observers: "ca-qc", name: "National Holiday (QC)", date: fixedDate(time.June, 24), observance: observeNextWeekday},
+	{observers: "ca", name: "Canada Day", date: fixedDate(time.July, 1), observance: observeNextWeekday},
+	{observers: "us", name: "Independence Day", date: fixedDate(time.July, 4), observance: observeNearestWeekday},
+	{observers: "ca-ab,ca-bc,ca-mb,ca-ns,ca-on", name: "Civic Day (AB, BC, ON, NS, MB)", date: nthWeekday(1, time.Monday, time.August)},
+	{observers: "uk", name: "Summer bank holiday", date: nthWeekday(-1, time.Monday, time.August)},
+	{observers: "ca,us", name: "Labor Day", date: nthWeekday(1, time.Monday, time.September)},
+	{observers: "ca", name: "Thanksgiving Day", date: nthWeekday(2, time.Monday, time.October)},
+	{observers: "ca-ab,ca-bc,ca-ns", name: "Remembrance Day (AB, BC, NS)", date: fixedDate(time.November, 11), observance: observeNextWeekday},
+	{observers: "us", name: "Thanksgiving Day", date: nthWeekday(4, time.Thursday, time.November)},
+	{observers: "us", name: "Day After Thanksgiving", date: daysAfter(nthWeekday(4, time.Thursday, time.November), 1)},
+	{observers: "us", name: "Christmas Eve", date: fixedDate(time.December, 24), observance: observePreviousWeekday},
+	{observers: "us", name: "Christmas Day", date: fixedDate(time.December, 25), observance: observeNearestWeekday},
+	{observers: "ca,uk", name: "Christmas Day", date: fixedDate(time.December, 25), observance: observeNextWeekday},
+	{observers: "ca,uk", name: "Boxing Day", date: fixedDate(time.December, 26), observance: observeNextWeekday},
+}
+
+// fixedDate is a holiday on the same day every year.
+func fixedDate(month time.Month, day int) holidayDateFunc {
+	return func(year int) civilDate {
+		return newCivilDate(year, month, day)
+	}
+}
+
+// nthWeekday is a holiday on the nth weekday of month, e.g. the third Monday.
+// A negative n counts from the end of the month, -1 is the last weekday.
+func nthWeekday(n int, weekday time.Weekday, month time.Month) holidayDateFunc {
+	return func(year int) civilDate {
+		if n < 0 {
+			last := newCivilDate(year, month+1, 0)
+			offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+			return last.AddDate(0, 0, -offset+7*(n+1))
+		}
+
+		first := newCivilDate(year, month, 1)
+		offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+		return first.AddDate(0, 0, offset+7*(n-1))
+	}
+}
+
+// weekdayBefore is a holiday on the last weekday before a given day, e.g. the
+// Monday before May 25th.
+func weekdayBefore(weekday time.Weekday, month time.Month, day int) holidayDateFunc {
+	return func(year int) civilDate {
+		before := newCivilDate(year, month, day-1)
+		offset := (int(before.Weekday()) - int(weekday) + 7) % 7
+		return before.AddDate(0, 0, -offset)
+	}
+}
+
+// easterOffset is a holiday a number of days from Easter Sunday.
+func easterOffset(days int) holidayDateFunc {
+	return func(year int) civilDate {
+		return easterSunday(year).AddDate(0, 0, days)
+	}
+}
+
+// daysAfter is a holiday a number of days after another holiday.
+func daysAfter(date holidayDateFunc, days int) holidayDateFunc {
+	return func(year int) civilDate {
+		return date(year).AddDate(0, 0, days)
+	}
+}
+
+// easterSunday returns the date of Easter Sunday in the Gregorian calendar
+// using the anonymous Gregorian algorithm.
+func easterSunday(year int) civilDate {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return newCivilDate(year, time.Month(month), day)
+}
+
+// applies returns true if the rule is in effect in year.
+func (r holidayRule) applies(year int) bool {
+	return (r.since == 0 || year >= r.since) && (r.until == 0 || year <= r.until)
+}
+
+// countries returns the countries and regions that observe the holiday.
+func (r holidayRule) countries() []string {
+	var countries []string
+	for _, country := range strings.Split(r.observers, ",") {
+		if country = strings.TrimSpace(country); country != "" {
+			countries = append(countries, country)
+		}
+	}
+
+	return countries
+}
+
+// observe returns the day the holiday on date is observed in a country, given
+// the days that are already holidays there, and whether it was moved.
+func (r holidayRule) observe(date civilDate, taken map[civilDate]bool) (civilDate, bool) {
+	step := 1
+	switch {
+	case r.observance == observeOnDay:
+		return date, false
+	case r.observance == observePreviousWeekday,
+		r.observance == observeNearestWeekday && date.Weekday() == time.Saturday:
+		step = -1
+	}
+
+	// A holiday is observed on a weekday whatever anyone's work week is.
+	observed := date
+	for {
+		if wd := observed.Weekday(); wd != time.Saturday && wd != time.Sunday && !taken[observed] {
+			return observed, !observed.Equal(date)
+		}
+
+		observed = observed.AddDate(0, 0, step)
+	}
+}
+
+// splitHolidayCountry splits a country code in to its country and region,
+// e.g. "ca-qc" in to "ca" and "qc".  The region of a bare country is empty.
+func splitHolidayCountry(code string) (country, region string) {
+	parts := strings.SplitN(code, "-", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+
+	return parts[0], parts[1]
+}
+
+// holidayRegions returns the regions of every country that observe a regional
+// holiday, e.g. "ca" has "ca-qc".
+func holidayRegions() map[string][]string {
+	regions := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, rule := range holidayRules {
+		for _, code := range rule.countries() {
+			country, region := splitHolidayCountry(code)
+			if region == "" || seen[code] {
+				continue
+			}
+
+			seen[code] = true
+			regions[country] = append(regions[country], code)
+		}
+	}
+
+	return regions
+}
+
+// holidayCalendar holds holiday names keyed by their date and then by country
+// or region.  A region's entry only holds the region's own holidays, lookup
+// adds the holidays of its country.
+type holidayCalendar map[civilDate]map[string]string
+
+// add sets a country or region's holiday on date, replacing any other holiday
+// of the country or region that day.  Setting a country's holiday also
+// replaces the holidays of its regions.
+func (c holidayCalendar) add(date civilDate, code, name string) {
+	if c[date] == nil {
+		c[date] = make(map[string]string)
+	}
+
+	if _, region := splitHolidayCountry(code); region == "" {
+		for other := range c[date] {
+			if strings.HasPrefix(other, code+"-") {
+				delete(c[date], other)
+			}
+		}
+	}
+
+	c[date][code] = name
+}
+
+// lookup returns the name of the holiday a country or region observes on
+// date, if any.  A region observes its country's holidays and its own.  A
+// country without a region observes the holidays of all of its regions, e.g.
+// "ca" observes both "ca-bc" and "ca-qc" holidays.
+func (c holidayCalendar) lookup(date civilDate, code string) (string, bool) {
+	holidays := c[date]
+	country, region := splitHolidayCountry(code)
+
+	var names []string
+	if name, found := holidays[country]; found {
+		names = append(names, name)
+	}
+
+	if region != "" {
+		if name, found := holidays[code]; found {
+			names = append(names, name)
+		}
+	} else {
+		var regional []string
+		seen := make(map[string]bool)
+		for other, name := range holidays {
+			if strings.HasPrefix(other, country+"-") && !seen[name] {
+				seen[name] = true
+				regional = append(regional, name)
+			}
+		}
+		sort.Strings(regional)
+		names = append(names, regional...)
+	}
+
+	if len(names) == 0 {
+		return "", false
+	}
+
+	return strings.Join(names, ", "), true
+}
+
+// computeHolidays evaluates holidayRules for year.  Holidays observed on a
+// substitute day may fall in the year before or after.
+func computeHolidays(year int) holidayCalendar {
+	cal := make(holidayCalendar)
+	regions := holidayRegions()
+
+	// The days that are already holidays, by country and region.  A
+	// country's holidays are also taken in each of its regions.
+	taken := make(map[string]map[civilDate]bool)
+	markTaken := func(code string, date civilDate) {
+		if taken[code] == nil {
+			taken[code] = make(map[civilDate]bool)
+		}
+
+		taken[code][date] = true
+	}
+
+	for _, rule := range holidayRules {
+		if !rule.applies(year) {
+			continue
+		}
+
+		date := rule.date(year)
+		for _, code := range rule.countries() {
+			observed, moved := rule.observe(date, taken[code])
+
+			name := rule.name
+			switch prev, found := cal[observed][code]; {
+			case moved:
+				name += ", observed"
+			case found:
+				// Two holidays on the same day.
+				name = prev + ", " + name
+			}
+
+			markTaken(code, observed)
+			if _, region := splitHolidayCountry(code); region == "" {
+				for _, regionCode := range regions[code] {
+					markTaken(regionCode, observed)
+				}
+			}
+
+			if cal[observed] == nil {
+				cal[observed] = make(map[string]string)
+			}
+			cal[observed][code] = name
+		}
+	}
+
+	return cal
+}
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestHolidayRules(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	for _, test := range []struct {
+		date    string
+		country string
+		want    string
+	}{
+		// The table of 2018 holidays the rules replaced.
+		{"2018-01-01", "uk", "New Year's Day"},
+		{"2018-01-15", "us", "Martin Luther King Day"},
+		{"2018-02-12", "ca", "Family Day (BC)"},
+		{"2018-02-19", "ca", "Family Day (AB, MB, ON, PE, SK)"},
+		{"2018-02-19", "us", "President's Day"},
+		{"2018-03-30", "uk", "Good Friday"},
+		{"2018-04-02", "uk", "Easter Monday"},
+		{"2018-04-13", "us", "Wellbeing Day"},
+		{"2018-05-07", "uk", "Early May bank holiday"},
+		{"2018-05-21", "ca", "Victoria Day"},
+		{"2018-05-28", "uk", "Spring bank holiday"},
+		{"2018-05-28", "us", "Memorial Day"},
+		{"2018-06-25", "ca", "National Holiday (QC), observed"},
+		{"2018-07-02", "ca", "Canada Day, observed"},
+		{"2018-07-04", "us", "Independence Day"},
+		{"2018-08-06", "ca", "Civic Day (AB, BC, ON, NS, MB)"},
+		{"2018-08-27", "uk", "Summer bank holiday"},
+		{"2018-09-03", "ca", "Labor Day"},
+		{"2018-10-08", "ca", "Thanksgiving Day"},
+		{"2018-11-12", "ca", "Remembrance Day (AB, BC, NS), observed"},
+		{"2018-11-22", "us", "Thanksgiving Day"},
+		{"2018-11-23", "us", "Day After Thanksgiving"},
+		{"2018-12-24", "us", "Christmas Eve"},
+		{"2018-12-25", "ca", "Christmas Day"},
+		{"2018-12-26", "uk", "Boxing Day"},
+
+		// Later years.
+		{"2019-02-18", "ca", "Family Day (AB, MB, ON, PE, SK), Family Day (BC)"},
+		{"2019-04-19", "ca", "Good Friday"},
+		{"2020-07-03", "us", "Independence Day, observed"},
+		{"2021-12-23", "us", "Christmas Day, observed"},
+		{"2021-12-24", "us", "Christmas Eve"},
+		{"2021-12-27", "uk", "Christmas Day, observed"},
+		{"2021-12-28", "uk", "Boxing Day, observed"},
+		{"2021-12-31", "us", "New Year's Day, observed"},
+		{"2022-01-03", "uk", "New Year's Day, observed"},
+		{"2024-03-29", "uk", "Good Friday"},
+		{"2030-11-28", "us", "Thanksgiving Day"},
+	} {
+		date, err := getDateInLocation(test.date)
+		if err != nil {
+			t.Fatalf("unable to parse %s: %v", test.date, err)
+		}
+
+		if got, _ := getCountryHolidayName(date, test.country); got != test.want {
+			t.Errorf("%s holiday on %s = %q, want %q", test.country, test.date, got, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		date    string
+		country string
+	}{
+		{"2018-03-30", "us"},
+		{"2018-05-29", "us"},
+		{"2021-12-25", "us"},
+		{"2022-01-01", "uk"},
+		{"2022-01-03", "us"},
+	} {
+		date, _ := getDateInLocation(test.date)
+		if name, found := getCountryHolidayName(date, test.country); found {
+			t.Errorf("%s is a %s holiday: %q", test.date, test.country, name)
+		}
+	}
+}
+
+func TestHolidayConfigOverrides(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyHolidays, map[string]string{
+		"2019-07-05": `us: Summer Break`,
+		"2019-12-25": `us: Christmas`,
+	})
+	resetHolidayCache()
+	defer func() {
+		viper.Set(configKeyHolidays, nil)
+		resetHolidayCache()
+	}()
+
+	for date, want := range map[string]string{
+		"2019-07-05": "Summer Break",
+		"2019-12-25": "Christmas",
+		"2019-07-04": "Independence Day",
+	} {
+		d, _ := getDateInLocation(date)
+		if got, _ := getCountryHolidayName(d, "us"); got != want {
+			t.Errorf("us holiday on %s = %q, want %q", date, got, want)
+		}
+	}
+
+	// Only the countries listed by the config are overridden.
+	d := newCivilDate(2019, time.December, 25)
+	if got, _ := getCountryHolidayName(d, "uk"); got != "Christmas Day" {
+		t.Errorf("uk holiday on 2019-12-25 = %q, want %q", got, "Christmas Day")
+	}
+}
+
+func TestRegionalHolidays(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	for _, test := range []struct {
+		date    string
+		country string
+		want    string
+	}{
+		{"2018-02-12", "ca-bc", "Family Day (BC)"},
+		{"2018-02-12", "ca-qc", ""},
+		{"2018-02-19", "ca-on", "Family Day (AB, MB, ON, PE, SK)"},
+		{"2018-02-19", "ca-bc", ""},
+		{"2018-06-25", "ca-qc", "National Holiday (QC), observed"},
+		{"2018-06-25", "ca-bc", ""},
+		{"2018-07-02", "ca-qc", "Canada Day, observed"},
+		{"2018-08-06", "ca-on", "Civic Day (AB, BC, ON, NS, MB)"},
+		{"2018-08-06", "ca-qc", ""},
+		{"2018-11-12", "ca-ns", "Remembrance Day (AB, BC, NS), observed"},
+		{"2018-11-12", "ca-on", ""},
+
+		// A region without regional holidays only observes its country's.
+		{"2018-05-21", "ca-yt", "Victoria Day"},
+		{"2018-08-06", "ca-yt", ""},
+	} {
+		date, _ := getDateInLocation(test.date)
+		if got, _ := getCountryHolidayName(date, test.country); got != test.want {
+			t.Errorf("%s holiday on %s = %q, want %q", test.country, test.date, got, test.want)
+		}
+	}
+
+	viper.Set(configKeyCountry, "ca-qc")
+	defer viper.Set(configKeyCountry, nil)
+
+	// Quebec observes its National Holiday but not BC's Family Day.
+	tuesday := newCivilDate(2018, time.June, 26)
+	if got, want := getPreviousWeekday(tuesday).Format(dateInputFormat), "2018-06-22"; got != want {
+		t.Errorf("previous weekday of 2018-06-26 = %s, want %s", got, want)
+	}
+
+	tuesday = newCivilDate(2018, time.February, 13)
+	if got, want := getPreviousWeekday(tuesday).Format(dateInputFormat), "2018-02-12"; got != want {
+		t.Errorf("previous weekday of 2018-02-13 = %s, want %s", got, want)
+	}
+}
+
+func TestRegionalHolidayConfigOverrides(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyHolidays, map[string]string{
+		"2018-08-06": `ca: Company Day`,
+		"2018-08-07": `ca-qc: Summer Break`,
+	})
+	resetHolidayCache()
+	defer func() {
+		viper.Set(configKeyHolidays, nil)
+		resetHolidayCache()
+	}()
+
+	for _, test := range []struct {
+		date    string
+		country string
+		want    string
+	}{
+		// A country's entry replaces the holidays of its regions.
+		{"2018-08-06", "ca-on", "Company Day"},
+		{"2018-08-06", "ca", "Company Day"},
+		{"2018-08-07", "ca-qc", "Summer Break"},
+		{"2018-08-07", "ca-on", ""},
+	} {
+		date, _ := getDateInLocation(test.date)
+		if got, _ := getCountryHolidayName(date, test.country); got != test.want {
+			t.Errorf("%s holiday on %s = %q, want %q", test.country, test.date, got, test.want)
+		}
+	}
+}
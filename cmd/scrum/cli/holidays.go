@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var holidaysCmd = &cobra.Command{
+	Use:          "holidays",
+	Short:        "List holidays",
+	SilenceUsage: true,
+	Long: `List the holidays of a country over the next year, or over a given year.
+
+The country is the configured country unless --country is given.`,
+	Example: `  $ scrum holidays                          # My upcoming holidays
+  $ scrum holidays --country uk --year 2019 # The UK's holidays in 2019
+  $ scrum holidays check                    # Check the holidays in the config file`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkRequiredFlags(cmd.Flags()); err != nil {
+			return errors.Wrap(err, "required flag missing")
+		}
+
+		return nil
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		today, err := getDateInLocation(dateToday)
+		if err != nil {
+			return errors.Wrap(err, "unable to get today's date")
+		}
+
+		since, until := today, today.AddDate(1, 0, -1)
+		if year := viper.GetInt(configKeyHolidayListYear); year != 0 {
+			since = newCivilDate(year, time.January, 1)
+			until = newCivilDate(year, time.December, 31)
+		}
+
+		return listHolidays(cmd.OutOrStdout(), viper.GetString(configKeyCountry), since, until)
+	},
+}
+
+var holidaysCheckCmd = &cobra.Command{
+	Use:          "check",
+	Short:        "Check the holidays in the config file",
+	SilenceUsage: true,
+	Long: `Check every entry of the holidays section of the config file and report the
+entries that can not be parsed, e.g. an invalid date or country list, broken
+country:"name" quoting, or an unreadable calendar file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return checkHolidays(cmd.OutOrStdout(), viper.GetStringMapString(configKeyHolidays))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(holidaysCmd)
+	holidaysCmd.AddCommand(holidaysCheckCmd)
+
+	{
+		const (
+			key          = configKeyHolidayListYear
+			longName     = "year"
+			shortName    = ""
+			defaultValue = 0
+			description  = "List the holidays of a year (defaults to the next twelve months)"
+		)
+
+		flags := holidaysCmd.Flags()
+		flags.IntP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+}
+
+// listHolidays prints the holidays country observes between since and until.
+func listHolidays(unbufOut io.Writer, country string, since, until civilDate) error {
+	w := bufio.NewWriter(unbufOut)
+	defer w.Flush()
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeaderLine(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+
+	table.SetHeader([]string{"date", "day", fmt.Sprintf("holiday (%s)", country)})
+	if viper.GetBool(configKeyLogTermColor) {
+		table.SetHeaderColor(
+			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+		)
+	}
+
+	var numHolidays int
+	for date := since; !date.After(until); date = date.AddDate(0, 0, 1) {
+		name, found := getCountryHolidayName(date, country)
+		if !found {
+			continue
+		}
+
+		table.Append([]string{date.Format(dateInputFormat), date.Format("Mon"), name})
+		numHolidays++
+	}
+
+	if numHolidays == 0 {
+		log.Warn().Str("country", country).Str("since", since.Format(dateInputFormat)).Str("until", until.Format(dateInputFormat)).Msg("no holidays found")
+		return nil
+	}
+
+	table.SetFooter([]string{"Holidays", "", fmt.Sprintf("%d", numHolidays)})
+	table.Render()
+
+	return nil
+}
+
+// checkHolidays reports every invalid entry of the holidays config.
+func checkHolidays(unbufOut io.Writer, holidays map[string]string) error {
+	w := bufio.NewWriter(unbufOut)
+	defer w.Flush()
+
+	keys := make([]string, 0, len(holidays))
+	for key := range holidays {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var numInvalid int
+	for _, key := range keys {
+		if err := checkHoliday(key, _Holiday(holidays[key])); err != nil {
+			fmt.Fprintf(w, "%s: %q: %v\n", key, holidays[key], err)
+			numInvalid++
+		}
+	}
+
+	if numInvalid > 0 {
+		return errors.Errorf("%d of %d holidays are invalid", numInvalid, len(holidays))
+	}
+
+	log.Info().Int("holidays", len(holidays)).Msg("every holiday is valid")
+
+	return nil
+}
+
+// checkHoliday returns the problem with a single entry of the holidays config,
+// if any.
+func checkHoliday(key string, h _Holiday) error {
+	if _, _, err := h.parse(); err != nil {
+		return err
+	}
+
+	if filename, found := h.calendarFile(); found {
+		_, err := readHolidayCalendar(filename)
+		return err
+	}
+
+	if _, err := time.Parse(dateInputFormat, key); err != nil {
+		return errors.Errorf("invalid date, format must be: %s", dateInputFormat)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestHolidayParse(t *testing.T) {
+	for _, test := range []struct {
+		holiday string
+		names   map[string]string
+		err     string
+	}{
+		{holiday: `us: Memorial Day`, names: map[string]string{"us": "Memorial Day"}},
+		{holiday: `ca, uk: Boxing Day`, names: map[string]string{"ca": "Boxing Day", "uk": "Boxing Day"}},
+		{holiday: `ca,us: ca:"Family Day" us:"President's Day"`, names: map[string]string{"ca": "Family Day", "us": "President's Day"}},
+		{holiday: `ca-qc: Fête nationale`, names: map[string]string{"ca-qc": "Fête nationale"}},
+		{holiday: `ca,ca-qc: ca:"Civic Day" ca-qc:"Summer Break"`, names: map[string]string{"ca": "Civic Day", "ca-qc": "Summer Break"}},
+		{holiday: `Memorial Day`, err: `missing ":"`},
+		{holiday: `us,: Memorial Day`, err: "empty country"},
+		{holiday: `u5: Memorial Day`, err: "invalid country"},
+		{holiday: `us:  `, err: "missing holiday name"},
+		{holiday: `ca,us: ca:"Family Day" us:President's Day"`, err: "missing opening quote"},
+		{holiday: `ca,us: ca:"Family Day" us:"President's Day`, err: "missing closing quote"},
+		{holiday: `ca,us: ca:"Family Day" uk:"Boxing Day"`, err: "not in the country list"},
+		{holiday: `ca,us: ca:"Family Day"`, err: "missing name for us's holiday"},
+	} {
+		countries, names, err := _Holiday(test.holiday).parse()
+		switch {
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%q: error = %v, want %q", test.holiday, err, test.err)
+		case test.err == "" && err != nil:
+			t.Errorf("%q: unexpected error: %v", test.holiday, err)
+		case test.err == "" && len(countries) != len(test.names):
+			t.Errorf("%q: countries = %v, want %d", test.holiday, countries, len(test.names))
+		}
+
+		for country, want := range test.names {
+			if got := names[country]; got != want {
+				t.Errorf("%q: %s's name = %q, want %q", test.holiday, country, got, want)
+			}
+		}
+	}
+}
+
+func TestHolidaysList(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	out := env.mustRun("holidays", "-C", "uk", "--year", "2019")
+	for _, want := range []string{"HOLIDAY (UK)", "2019-04-19", "Good Friday", "2019-12-26", "Boxing Day"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "Thanksgiving") {
+		t.Errorf("output contains another country's holiday:\n%s", out)
+	}
+
+	// The upcoming holidays start today, March 12th 2018.
+	out = env.mustRun("holidays")
+	for _, want := range []string{"2018-04-13", "2019-02-18"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "2018-02-19") || strings.Contains(out, "2019-03-") {
+		t.Errorf("output contains holidays outside of the next year:\n%s", out)
+	}
+}
+
+func TestHolidaysCheck(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("holidays", "check")
+
+	viper.Set(configKeyHolidays, map[string]string{
+		"2019-07-05": `us: Summer Break`,
+		"2019-07-32": `us: Not a Day`,
+		"2019-08-01": `us Missing Colon`,
+		"2019-08-02": `ca,us: ca:"Broken Quote us:"Day"`,
+		"company":    `us: ` + env.dir + `/missing.ics`,
+	})
+	resetHolidayCache()
+	defer func() {
+		viper.Set(configKeyHolidays, nil)
+		resetHolidayCache()
+	}()
+
+	out, err := env.run("holidays", "check")
+	if err == nil || !strings.Contains(err.Error(), "4 of 5") {
+		t.Errorf("error = %v, want 4 of 5 invalid holidays", err)
+	}
+
+	for _, want := range []string{"2019-07-32", "2019-08-01", "2019-08-02", "company"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not report %q:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "Summer Break") {
+		t.Errorf("output reports a valid holiday:\n%s", out)
+	}
+
+	// Invalid holidays are skipped rather than panicking.
+	env.mustRun("set", "-u", "alice", "-D", "2019-07-31", "-v", "3")
+}
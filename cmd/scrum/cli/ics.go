@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// icsDateLayout and icsDateTimeLayout are the layouts of iCalendar DATE
+	// and UTC DATE-TIME values, icsLocalDateTimeLayout is the layout of a
+	// DATE-TIME value without the UTC suffix.
+	icsDateLayout          = "20060102"
+	icsDateTimeLayout      = "20060102T150405Z"
+	icsLocalDateTimeLayout = "20060102T150405"
+
+	// icsLineLength is the maximum length of a content line in octets,
+	// excluding the line break.
+	icsLineLength = 75
+
+	icsProductID = "-//gwydirsam//go-scrum//EN"
+)
+
+// icsEvent is an iCalendar VEVENT reduced to the days it covers.
+type icsEvent struct {
+	UID     string
+	Summary string
+
+	// Start and End are the first and last day of the event, inclusive.
+	Start civilDate
+	End   civilDate
+}
+
+// icsUnfold returns the content lines of an iCalendar stream.  A line that
+// begins with a space or tab continues the line before it.
+func icsUnfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			continue
+		case (line[0] == ' ' || line[0] == '\t') && len(lines) > 0:
+			lines[len(lines)-1] += line[1:]
+		default:
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// icsSplitLine splits a content line in to its name, parameters and value,
+// e.g. "DTSTART;VALUE=DATE:20180101".
+func icsSplitLine(line string) (name string, params map[string]string, value string) {
+	var quoted bool
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+
+	if colon == -1 {
+		return strings.ToUpper(line), nil, ""
+	}
+
+	params = make(map[string]string)
+	fields := strings.Split(line[:colon], ";")
+	for _, param := range fields[1:] {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+
+	return strings.ToUpper(fields[0]), params, line[colon+1:]
+}
+
+// icsParseDate parses a DATE or DATE-TIME value as a day.  A UTC DATE-TIME,
+// or one with a TZID parameter, is converted to loc first.  Any other
+// DATE-TIME is taken to be local to loc, as is one whose time zone is unknown.
+// The second return value is true when the value has a time of day other than
+// midnight.
+func icsParseDate(value string, params map[string]string, loc *time.Location) (civilDate, bool, error) {
+	var tz *time.Location
+	switch tzid := params["TZID"]; {
+	case strings.HasSuffix(value, "Z"):
+		tz = time.UTC
+		value = strings.TrimSuffix(value, "Z")
+	case tzid != "" && len(value) > len(icsDateLayout):
+		var err error
+		if tz, err = time.LoadLocation(tzid); err != nil {
+			log.Warn().Err(err).Str("tzid", tzid).Msg("unknown time zone, using the local time zone")
+			tz = nil
+		}
+	}
+
+	if tz != nil {
+		t, err := time.ParseInLocation(icsLocalDateTimeLayout, value, tz)
+		if err != nil {
+			return civilDate{}, false, errors.Wrapf(err, "invalid date-time %q", value)
+		}
+
+		t = t.In(loc)
+		day := dateOf(t)
+		return day, !t.Equal(day.In(loc)), nil
+	}
+
+	if len(value) < len(icsDateLayout) {
+		return civilDate{}, false, errors.Errorf("invalid date %q", value)
+	}
+
+	day, err := parseCivilDate(icsDateLayout, value[:len(icsDateLayout)])
+	if err != nil {
+		return civilDate{}, false, errors.Wrapf(err, "invalid date %q", value)
+	}
+
+	timeOfDay := strings.TrimLeft(value[len(icsDateLayout):], "T")
+	return day, strings.Trim(timeOfDay, "0") != "", nil
+}
+
+// icsUnescape reverses the escaping of an iCalendar TEXT value.
+func icsUnescape(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, " ", `\N`, " ").Replace(value)
+}
+
+// icsEscape escapes an iCalendar TEXT value.
+func icsEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(value)
+}
+
+// parseICS returns the events of an iCalendar stream.  Only the fields
+// needed to place an event on the calendar are read, recurrence rules are not
+// supported.
+func parseICS(r io.Reader, loc *time.Location) ([]*icsEvent, error) {
+	lines, err := icsUnfold(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read calendar")
+	}
+
+	var events []*icsEvent
+	var ev *icsEvent
+	var endExclusive bool
+	for i, line := range lines {
+		name, params, value := icsSplitLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			ev = &icsEvent{}
+			endExclusive = false
+		case ev == nil:
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			switch {
+			case ev.Start.IsZero():
+				return nil, errors.Errorf("line %d: event %q has no start date", i+1, ev.Summary)
+			case ev.End.IsZero():
+				ev.End = ev.Start
+			case endExclusive && ev.End.After(ev.Start):
+				ev.End = ev.End.AddDate(0, 0, -1)
+			}
+
+			events = append(events, ev)
+			ev = nil
+		case name == "UID":
+			ev.UID = value
+		case name == "SUMMARY":
+			ev.Summary = icsUnescape(value)
+		case name == "DTSTART":
+			if ev.Start, _, err = icsParseDate(value, params, loc); err != nil {
+				return nil, errors.Wrapf(err, "line %d", i+1)
+			}
+		case name == "DTEND":
+			var hasTime bool
+			if ev.End, hasTime, err = icsParseDate(value, params, loc); err != nil {
+				return nil, errors.Wrapf(err, "line %d", i+1)
+			}
+
+			// The end of an event is exclusive, so an event ending at midnight
+			// (or on a DATE) does not cover its end day.
+			endExclusive = !hasTime || params["VALUE"] == "DATE"
+		}
+	}
+
+	return events, nil
+}
+
+// icsWriter writes an iCalendar stream with CRLF line breaks and long lines
+// folded.
+type icsWriter struct {
+	w   *bufio.Writer
+	now time.Time
+}
+
+func newICSWriter(w io.Writer, name string) *icsWriter {
+	iw := &icsWriter{
+		w:   bufio.NewWriter(w),
+		now: timeNow().UTC(),
+	}
+
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:" + icsProductID)
+	iw.line("CALSCALE:GREGORIAN")
+	iw.line("X-WR-CALNAME:" + icsEscape(name))
+
+	return iw
+}
+
+// line writes a content line, folding it at icsLineLength octets without
+// splitting a UTF-8 sequence.
+func (iw *icsWriter) line(s string) {
+	limit := icsLineLength
+	for len(s) > limit {
+		n := limit
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+
+		iw.w.WriteString(s[:n] + "\r\n ")
+		s = s[n:]
+
+		// The leading space of a continuation line counts toward its length.
+		limit = icsLineLength - 1
+	}
+
+	iw.w.WriteString(s + "\r\n")
+}
+
+// event writes an all-day event.
+func (iw *icsWriter) event(ev *icsEvent, category string) {
+	iw.line("BEGIN:VEVENT")
+	iw.line("UID:" + ev.UID)
+	iw.line("DTSTAMP:" + iw.now.Format(icsDateTimeLayout))
+	iw.line("DTSTART;VALUE=DATE:" + ev.Start.Format(icsDateLayout))
+	iw.line("DTEND;VALUE=DATE:" + ev.End.AddDate(0, 0, 1).Format(icsDateLayout))
+	iw.line("SUMMARY:" + icsEscape(ev.Summary))
+	iw.line("CATEGORIES:" + icsEscape(category))
+	iw.line("TRANSP:TRANSPARENT")
+	iw.line("END:VEVENT")
+}
+
+// Close ends the calendar and flushes it.
+func (iw *icsWriter) Close() error {
+	iw.line("END:VCALENDAR")
+
+	if err := iw.w.Flush(); err != nil {
+		return errors.Wrap(err, "unable to write calendar")
+	}
+
+	return nil
+}
+
+// icsUID returns a UID for an exported event from its parts.
+func icsUID(parts ...string) string {
+	uid := strings.ToLower(strings.Join(parts, "-"))
+	uid = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, uid)
+
+	return fmt.Sprintf("%s@go-scrum", uid)
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const testHolidayCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1@example.com\r\n" +
+	"DTSTART;VALUE=DATE:20190614\r\n" +
+	"DTEND;VALUE=DATE:20190615\r\n" +
+	"SUMMARY:Company Day\\, Summer\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:2@example.com\r\n" +
+	"DTSTART;VALUE=DATE:20191226\r\n" +
+	"DTEND;VALUE=DATE:20191228\r\n" +
+	"SUMMARY:Winter\r\n" +
+	"  Break\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	events, err := parseICS(strings.NewReader(testHolidayCalendar), time.UTC)
+	if err != nil {
+		t.Fatalf("unable to parse calendar: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	for i, want := range []struct {
+		summary    string
+		start, end string
+	}{
+		{"Company Day, Summer", "2019-06-14", "2019-06-14"},
+		{"Winter Break", "2019-12-26", "2019-12-27"},
+	} {
+		ev := events[i]
+		if ev.Summary != want.summary || ev.Start.Format(dateInputFormat) != want.start || ev.End.Format(dateInputFormat) != want.end {
+			t.Errorf("event %d = %q %s..%s, want %q %s..%s", i, ev.Summary, ev.Start.Format(dateInputFormat), ev.End.Format(dateInputFormat), want.summary, want.start, want.end)
+		}
+	}
+}
+
+func TestParseICSTimeZones(t *testing.T) {
+	const calendar = "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Evening\r\n" +
+		"DTSTART;TZID=America/Los_Angeles:20180312T200000\r\n" +
+		"DTEND;TZID=America/Los_Angeles:20180312T210000\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Unknown\r\n" +
+		"DTSTART;TZID=Nowhere Standard Time:20180312T200000\r\n" +
+		"DTEND;TZID=Nowhere Standard Time:20180312T210000\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := parseICS(strings.NewReader(calendar), time.UTC)
+	if err != nil {
+		t.Fatalf("unable to parse calendar: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	// 8pm in Los Angeles is the next day in UTC, an unknown time zone is
+	// taken to be UTC.
+	for i, want := range []string{"2018-03-13", "2018-03-12"} {
+		ev := events[i]
+		if ev.Start.Format(dateInputFormat) != want || ev.End.Format(dateInputFormat) != want {
+			t.Errorf("event %q = %s..%s, want %s..%s", ev.Summary, ev.Start.Format(dateInputFormat), ev.End.Format(dateInputFormat), want, want)
+		}
+	}
+}
+
+func TestHolidayCalendarImport(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyHolidays, map[string]string{
+		"company":    "ca,us: " + env.writeFile("company.ics", testHolidayCalendar),
+		"2019-12-27": "us: Not Winter Break",
+	})
+	resetHolidayCache()
+	defer func() {
+		viper.Set(configKeyHolidays, nil)
+		resetHolidayCache()
+	}()
+
+	for _, test := range []struct {
+		date, country, want string
+	}{
+		{"2019-06-14", "ca", "Company Day, Summer"},
+		{"2019-06-14", "us", "Company Day, Summer"},
+		{"2019-12-26", "us", "Winter Break"},
+		{"2019-12-27", "ca", "Winter Break"},
+		{"2019-12-27", "us", "Not Winter Break"},
+	} {
+		date, _ := getDateInLocation(test.date)
+		if got, _ := getCountryHolidayName(date, test.country); got != test.want {
+			t.Errorf("%s holiday on %s = %q, want %q", test.country, test.date, got, test.want)
+		}
+	}
+
+	date, _ := getDateInLocation("2019-06-14")
+	if name, found := getCountryHolidayName(date, "uk"); found {
+		t.Errorf("uk observes an imported holiday: %q", name)
+	}
+}
+
+func TestCalendarExport(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/team.json", []byte(testTeamRoster))
+	env.mustRun("set", "-u", "alice", "-v", "3")
+
+	filename := env.writeFile("scrum.ics", "")
+	env.mustRun("calendar", "export", "--until", "2018-04-13", "-o", filename)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("unable to open calendar: %v", err)
+	}
+	defer f.Close()
+
+	events, err := parseICS(f, time.UTC)
+	if err != nil {
+		t.Fatalf("unable to parse exported calendar: %v", err)
+	}
+
+	found := make(map[string]string)
+	for _, ev := range events {
+		found[ev.Summary] = ev.Start.Format(dateInputFormat) + ".." + ev.End.Format(dateInputFormat)
+	}
+
+	// Canada is not on the roster.
+	for summary, want := range map[string]string{
+		"alice: vacation":    "2018-03-12..2018-03-14",
+		"Good Friday (uk)":   "2018-03-30..2018-03-30",
+		"Easter Monday (uk)": "2018-04-02..2018-04-02",
+		"Wellbeing Day (us)": "2018-04-13..2018-04-13",
+	} {
+		if got := found[summary]; got != want {
+			t.Errorf("event %q = %q, want %q", summary, got, want)
+		}
+	}
+
+	if len(found) != 4 {
+		t.Errorf("got %d events, want 4: %v", len(found), found)
+	}
+}
+
+func TestCalendarExportOnlyStatsLeaves(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("set", "-u", "alice", "-v", "3")
+	env.manta.PutObject("stor/scrum/2018/03/12/bob", []byte(strings.Repeat("Reviewed TRITON-123\n", 4)))
+
+	var lock sync.Mutex
+	var heads []string
+	env.manta.OnRequest(func(r *http.Request) {
+		if r.Method == http.MethodHead {
+			lock.Lock()
+			heads = append(heads, r.URL.Path)
+			lock.Unlock()
+		}
+	})
+
+	out := env.mustRun("calendar", "export", "--until", "2018-03-16")
+	if !strings.Contains(out, "SUMMARY:alice: vacation") {
+		t.Errorf("alice's vacation was not exported:\n%s", out)
+	}
+
+	for _, path := range heads {
+		if strings.HasSuffix(path, "/bob") {
+			t.Errorf("exporting the calendar checked bob's scrum: %s", path)
+		}
+	}
+}
+
+func TestICSWriterFoldsLines(t *testing.T) {
+	var buf strings.Builder
+	iw := newICSWriter(&buf, "Scrum")
+	iw.event(&icsEvent{
+		UID:     "long@go-scrum",
+		Summary: strings.Repeat("é", 60),
+		Start:   newCivilDate(2018, time.March, 12),
+		End:     newCivilDate(2018, time.March, 12),
+	}, "Holiday")
+	if err := iw.Close(); err != nil {
+		t.Fatalf("unable to write calendar: %v", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > icsLineLength {
+			t.Errorf("line is %d octets long: %q", len(line), line)
+		}
+	}
+
+	events, err := parseICS(strings.NewReader(buf.String()), time.UTC)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("unable to parse written calendar: %v", err)
+	}
+
+	if got, want := events[0].Summary, strings.Repeat("é", 60); got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
@@ -10,7 +10,6 @@ import (
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
-	"github.com/sean-/conswriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -28,7 +27,10 @@ var initCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var b bytes.Buffer
 		b.WriteString("[general]\n")
-		b.WriteString(fmt.Sprintf("country  = %+q\n", viper.GetString(configKeyCountry)))
+		b.WriteString(fmt.Sprintf("country        = %+q\n", viper.GetString(configKeyCountry)))
+		b.WriteString(fmt.Sprintf("#work-week     = %+q\n", viper.GetString(configKeyWorkWeek)))
+		b.WriteString(fmt.Sprintf("#day-starts-at = %+q # scrums before this time are for the day before\n", viper.GetString(configKeyDayStartsAt)))
+		b.WriteString(fmt.Sprintf("#tz            = %+q\n", "America/Toronto"))
 		b.WriteString("\n")
 
 		b.WriteString("[scrum]\n")
@@ -60,10 +62,15 @@ var initCmd = &cobra.Command{
 		b.WriteString(fmt.Sprintf("timeout       = %+q\n", viper.GetDuration(configKeyMantaTimeout)))
 		b.WriteString(fmt.Sprintf("url           = %+q\n", viper.GetString(configKeyMantaURL)))
 		b.WriteString(fmt.Sprintf("user          = %+q\n", interpolateMantaUserEnvVar(viper.GetString(configKeyMantaUser))))
+		b.WriteString("\n")
+
+		b.WriteString("[storage]\n")
+		b.WriteString(fmt.Sprintf("backend    = %+q\n", viper.GetString(configKeyStorageBackend)))
+		b.WriteString(fmt.Sprintf("#directory = %+q # scrum directory for the %q backend\n", "~/scrum", storageBackendLocal))
 
 		rawFilename := viper.GetString(configKeyInitFilename)
 		if rawFilename == "-" {
-			b.WriteTo(conswriter.GetTerminal())
+			b.WriteTo(cmd.OutOrStdout())
 			return nil
 		}
 
@@ -2,16 +2,12 @@ package cli
 
 import (
 	"bufio"
-	"context"
 	"fmt"
-	"path"
-	"time"
+	"io"
 
-	"github.com/joyent/triton-go/storage"
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
-	"github.com/sean-/conswriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -52,9 +48,24 @@ func init() {
 			key         = configKeyListInputDate
 			longName    = "date"
 			shortName   = "D"
-			description = "Date for scrum"
+			description = "Date or range of dates for scrum (e.g. \"yesterday\" or \"this week\")"
 		)
-		defaultValue := time.Now().Format(dateInputFormat)
+		defaultValue := dateToday
+
+		flags := listCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key         = configKeyListOutput
+			longName    = "output"
+			shortName   = "o"
+			description = outputFormatDescription
+		)
+		defaultValue := _OutputFormatText.String()
 
 		flags := listCmd.Flags()
 		flags.StringP(longName, shortName, defaultValue, description)
@@ -97,8 +108,10 @@ var listCmd = &cobra.Command{
 	Short:        "List scrum information",
 	Long:         `List scrum information for the day`,
 	SilenceUsage: true,
-	Example: `  $ scrum list                      # List scrummers for the day
-  $ scrum list -t`,
+	Example: `  $ scrum list                           # List scrummers for the day
+  $ scrum list -t
+  $ scrum list -D 2018-03-01..2018-03-09 # List scrummers for each day of a range
+  $ scrum list -o csv                    # List scrummers for the day as CSV`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		if err := checkRequiredFlags(cmd.Flags()); err != nil {
 			return errors.Wrap(err, "required flag missing")
@@ -112,56 +125,152 @@ var listCmd = &cobra.Command{
 	},
 
 	RunE: func(cmd *cobra.Command, args []string) error {
-		client, err := getScrumClient()
+		format, err := getOutputFormat(configKeyListOutput)
 		if err != nil {
-			return errors.Wrap(err, "unable to create a new scrum client")
+			return err
 		}
-		defer client.dumpMantaClientStats()
 
-		scrumDate, err := getDateInLocation(viper.GetString(configKeyListInputDate))
+		store, err := getScrumStore()
+		if err != nil {
+			return errors.Wrap(err, "unable to create a new scrum store")
+		}
+		defer dumpStoreStats(store)
+
+		scrumDate, until, err := getDateRangeInLocation(viper.GetString(configKeyListInputDate))
 		if err != nil {
 			return errors.Wrap(err, "unable to parse scrum date")
 		}
 
+		isRange := !scrumDate.Equal(until)
+
+		days := []civilDate{scrumDate}
 		switch {
+		case isRange && (viper.GetBool(configKeyListTomorrow) || viper.GetBool(configKeyListYesterday)):
+			return errors.New("tomorrow and yesterday can not be used with a range of dates")
+		case isRange:
+			if days = getBusinessDays(scrumDate, until); len(days) == 0 {
+				return errors.Errorf("no business days between %s and %s", scrumDate, until)
+			}
 		case viper.GetBool(configKeyListTomorrow):
-			scrumDate = getNextWeekday(scrumDate)
+			days[0] = getNextWeekday(scrumDate)
 		case viper.GetBool(configKeyListYesterday):
-			scrumDate = getPreviousWeekday(scrumDate)
+			days[0] = getPreviousWeekday(scrumDate)
 		}
 
-		return listScrummers(client, scrumDate)
+		w := cmd.OutOrStdout()
+		if format != _OutputFormatText {
+			return listScrumRecords(w, store, format, days)
+		}
+
+		for _, date := range days {
+			if isRange {
+				writeDateHeading(w, date)
+			}
+
+			if err := listScrummers(w, store, date); err != nil {
+				return err
+			}
+		}
+
+		return nil
 	},
 }
 
+// listScrumRecords writes the metadata of every scrum for each day in a
+// machine-readable format.  Like listScrummers, users on the out of office
+// schedule who have not scrummed are included with their status.
+func listScrumRecords(w io.Writer, store ScrumStore, format _OutputFormat, days []civilDate) error {
+	cal, err := loadOOOCalendar()
+	if err != nil {
+		return err
+	}
+
+	var records []scrumRecord
+	var numErrors, numScrums int
+	for _, date := range days {
+		entries, err := listDay(cmdCtx, store, date)
+		if err != nil {
+			return errors.Wrap(err, "unable to list scrum directory")
+		}
+
+		scrummed := make(map[string]bool, len(entries))
+		reqs := make([]scrumRequest, 0, len(entries))
+		for _, ent := range entries {
+			scrummed[ent.Name] = true
+			if v, found := usernameActionMap[ent.Name]; found && v == _Ignore {
+				continue
+			}
+
+			reqs = append(reqs, scrumRequest{date: date, user: ent.Name})
+		}
+		numScrums += len(reqs)
+
+		for _, r := range statScrums(cmdCtx, store, reqs, getConcurrency()) {
+			obj, err := r.wait()
+			if err != nil {
+				log.Error().Err(err).Str("username", r.user).Str("date", date.String()).Msg("unable to stat user's scrum")
+				numErrors++
+				continue
+			}
+
+			records = append(records, newScrumRecord(date, obj, false))
+		}
+
+		for _, p := range cal.overlapping(date, date) {
+			if !scrummed[p.User] {
+				records = append(records, scrumRecord{User: p.User, Date: date.String(), Status: p.Status, Until: p.End.String()})
+			}
+		}
+	}
+
+	if err := writeScrumRecords(w, format, records, false); err != nil {
+		return err
+	}
+
+	if numErrors > 0 {
+		return errors.Errorf("unable to stat %d of %d scrums", numErrors, numScrums)
+	}
+
+	return nil
+}
+
 // listScrummers prints every user who scrummed
-func listScrummers(c *scrumClient, scrumDate time.Time) error {
-	scrumPath := path.Join("stor", "scrum", scrumDate.Format(scrumDateLayout))
-
-	ctx, _ := context.WithTimeout(context.Background(), viper.GetDuration(configKeyMantaTimeout))
-	start := time.Now()
-	dirEnts, err := c.Dir().List(ctx, &storage.ListDirectoryInput{
-		DirectoryName: scrumPath,
-	})
-	elapsed := time.Now().Sub(start)
-	log.Debug().Str("path", scrumPath).Str("duration", elapsed.String()).Msg("ListDirectory")
-	c.Histogram.RecordValue(float64(elapsed) / float64(time.Second))
-	c.listCalls++
+func listScrummers(unbufOut io.Writer, store ScrumStore, scrumDate civilDate) error {
+	entries, err := listDay(cmdCtx, store, scrumDate)
 	if err != nil {
-		return errors.Wrap(err, "unable to list manta directory")
+		return errors.Wrap(err, "unable to list scrum directory")
+	}
+
+	cal, err := loadOOOCalendar()
+	if err != nil {
+		return err
+	}
+
+	// Users on the out of office schedule who have not scrummed are listed
+	// with their status.
+	scrummed := make(map[string]bool, len(entries))
+	for _, ent := range entries {
+		scrummed[ent.Name] = true
 	}
 
-	if dirEnts.ResultSetSize == 0 {
+	var out []*oooPeriod
+	for _, p := range cal.overlapping(scrumDate, scrumDate) {
+		if !scrummed[p.User] {
+			out = append(out, p)
+		}
+	}
+
+	if len(entries) == 0 && len(out) == 0 {
 		log.Warn().Msg("no users have scrummed yet")
 		return nil
 	}
 
-	w := bufio.NewWriter(conswriter.GetTerminal())
+	w := bufio.NewWriter(unbufOut)
 	defer w.Flush()
 
 	switch {
 	case viper.IsSet(configKeyListUsersOne) && viper.GetBool(configKeyListUsersOne):
-		for _, ent := range dirEnts.Entries {
+		for _, ent := range entries {
 			if v, found := usernameActionMap[ent.Name]; found && v == _Ignore {
 				continue
 			}
@@ -171,50 +280,69 @@ func listScrummers(c *scrumClient, scrumDate time.Time) error {
 
 		return nil
 	case viper.GetBool(configKeyListUsersAll):
-		var tz string
-		if viper.GetBool(configKeyUseUTC) {
-			tz, _ = scrumDate.UTC().Zone()
-		} else {
-			tz, _ = scrumDate.Local().Zone()
-		}
+		tz, _ := scrumDate.In(displayLocation()).Zone()
 
 		table := tablewriter.NewWriter(w)
 		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
 		table.SetHeaderLine(false)
 		table.SetAutoFormatHeaders(true)
 
-		table.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT})
+		table.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
 		table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
 		table.SetCenterSeparator("")
 		table.SetColumnSeparator("")
 		table.SetRowSeparator("")
 
-		table.SetHeader([]string{"name", "size", fmt.Sprintf("mtime (%s)", tz)})
+		table.SetHeader([]string{"name", "size", fmt.Sprintf("mtime (%s)", tz), "status", "until", "author"})
 		if viper.GetBool(configKeyLogTermColor) {
 			table.SetHeaderColor(
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+				tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+				tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+				tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
 			)
 		}
 
 		const mtimeFormat = "2006-01-02 15:04:05"
 
-		var numScrum uint
-		for _, ent := range dirEnts.Entries {
+		// The directory listing doesn't include each scrum's metadata.
+		reqs := make([]scrumRequest, 0, len(entries))
+		for _, ent := range entries {
 			if v, found := usernameActionMap[ent.Name]; found && v == _Ignore {
 				continue
 			}
 
-			mtime := ent.ModifiedTime
-			if !viper.GetBool(configKeyUseUTC) {
-				mtime = mtime.Local()
+			reqs = append(reqs, scrumRequest{date: scrumDate, user: ent.Name})
+		}
+
+		var numScrum uint
+		for _, r := range statScrums(cmdCtx, store, reqs, getConcurrency()) {
+			obj, err := r.wait()
+			if err != nil {
+				log.Error().Err(err).Str("username", r.user).Msg("unable to stat user's scrum")
+				table.Append([]string{r.user, "?", "?", "?", "", ""})
+				numScrum++
+				continue
+			}
+
+			mtime := displayTime(obj.ModifiedTime)
+
+			var endDate string
+			if !obj.Metadata.EndDate.IsZero() {
+				endDate = obj.Metadata.EndDate.Format(scrumMetadataDateLayout)
 			}
 
-			table.Append([]string{ent.Name, fmt.Sprintf("%d", ent.Size), mtime.Format(mtimeFormat)})
+			table.Append([]string{obj.Name, fmt.Sprintf("%d", obj.Size), mtime.Format(mtimeFormat), obj.Metadata.Status, endDate, obj.Metadata.Author})
 			numScrum++
 		}
-		table.SetFooter([]string{"Total", fmt.Sprintf("%d", numScrum), ""})
+
+		for _, p := range out {
+			table.Append([]string{p.User, "-", "-", p.Status, p.End.Format(scrumMetadataDateLayout), ""})
+		}
+
+		table.SetFooter([]string{"Total", fmt.Sprintf("%d", numScrum), "", "", "", ""})
 
 		table.Render()
 
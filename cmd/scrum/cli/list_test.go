@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListUsernames(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/bob", []byte("bob's scrum\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("alice's scrum\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/all", []byte("everyone\n"))
+
+	out := env.mustRun("list", "-1")
+	if got, want := out, "alice\nbob\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestListTable(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("alice's scrum\n"))
+
+	out := env.mustRun("ls")
+	for _, want := range []string{"MTIME (UTC)", "alice", "14", "2018-03-12 10:00:00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
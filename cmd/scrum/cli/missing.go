@@ -0,0 +1,344 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// missingLookbackDays is how many days before the date being checked are
+// searched for a vacation or sick leave scrum that covers it.
+const missingLookbackDays = 30
+
+// missingStatusForgot is the status of a member who has not scrummed and is
+// neither away nor on a holiday.
+const missingStatusForgot = "missing"
+
+var missingCmd = &cobra.Command{
+	Args:         cobra.NoArgs,
+	Use:          "missing",
+	Short:        "List team members who have not scrummed",
+	SilenceUsage: true,
+	Long: `List the members of the team roster who have not scrummed for a day.  Members
+on vacation or sick leave, or observing a holiday in their country, are listed
+separately from those who simply forgot.  Vacations and sick leaves are read
+from scrums and from the out of office schedule, ooo.file.
+
+The roster is read from team.file, or from team.json in the scrum directory.`,
+	Example: `  $ scrum missing                  # Who hasn't scrummed today?
+  $ scrum missing -y --manager bob # Who of bob's reports didn't scrum yesterday?`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkRequiredFlags(cmd.Flags()); err != nil {
+			return errors.Wrap(err, "required flag missing")
+		}
+
+		return nil
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getScrumStore()
+		if err != nil {
+			return errors.Wrap(err, "unable to create a new scrum store")
+		}
+		defer dumpStoreStats(store)
+
+		scrumDate, err := getDateInLocation(viper.GetString(configKeyMissingInputDate))
+		if err != nil {
+			return errors.Wrap(err, "unable to parse scrum date")
+		}
+
+		if viper.GetBool(configKeyMissingYesterday) {
+			scrumDate = getPreviousWeekday(scrumDate)
+		}
+
+		roster, err := loadTeamRoster(cmdCtx, store)
+		if err != nil {
+			return err
+		}
+
+		return listMissing(cmd.OutOrStdout(), store, roster, scrumDate, viper.GetString(configKeyMissingManager))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(missingCmd)
+
+	{
+		const (
+			key         = configKeyMissingInputDate
+			longName    = "date"
+			shortName   = "D"
+			description = "Date to check"
+		)
+		defaultValue := dateToday
+
+		flags := missingCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key               = configKeyMissingYesterday
+			longOpt, shortOpt = "yesterday", "y"
+			defaultValue      = false
+		)
+		flags := missingCmd.Flags()
+		flags.BoolP(longOpt, shortOpt, defaultValue, "Check the previous weekday")
+		viper.BindPFlag(key, flags.Lookup(longOpt))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeyMissingManager
+			longName     = "manager"
+			shortName    = ""
+			defaultValue = ""
+			description  = "Only check the members reporting to this manager"
+		)
+
+		flags := missingCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeyTeamFile
+			longName     = "team-file"
+			shortName    = ""
+			defaultValue = ""
+			description  = "Team roster file (defaults to team.json in the scrum directory)"
+		)
+
+		flags := missingCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+}
+
+// missingMember is a member of the roster who has not scrummed.
+type missingMember struct {
+	*teamMember
+
+	// status is missingStatusForgot, the member's vacation or sick leave or
+	// the holiday they observe.
+	status string
+}
+
+// findMissing returns the members of roster reporting to manager (or every
+// member) who have not scrummed for scrumDate or are away, members who forgot
+// first.  Members are away when their scrums or the out of office schedule,
+// ooo.file, say so.
+func findMissing(store ScrumStore, roster teamRoster, scrumDate civilDate, manager string) ([]*missingMember, error) {
+	ctx := cmdCtx
+
+	cal, err := loadOOOCalendar()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := listScrumDays(ctx, store, []civilDate{scrumDate}, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	scrummed := make(map[string]bool, len(entries[0]))
+	for _, ent := range entries[0] {
+		scrummed[ent.Name] = true
+	}
+
+	var missing []*missingMember
+	var forgot []*missingMember
+	var reqs []scrumRequest
+	for _, member := range roster.members() {
+		if manager != "" && member.Manager != manager {
+			continue
+		}
+
+		if !getWorkWeek(member.User).has(scrumDate.Weekday()) {
+			continue
+		}
+
+		// Every day of a vacation or sick leave has a scrum, which is checked
+		// below.
+		if scrummed[member.User] {
+			reqs = append(reqs, scrumRequest{date: scrumDate, user: member.User})
+			continue
+		}
+
+		m := &missingMember{teamMember: member, status: missingStatusForgot}
+		if holidayName, found := getCountryHolidayName(scrumDate, member.Country); found {
+			m.status = "holiday: " + holidayName
+		} else if p, out := cal.lookup(member.User, scrumDate); out {
+			m.status = p.Status + " until " + p.End.Format(scrumMetadataDateLayout)
+		} else {
+			forgot = append(forgot, m)
+		}
+
+		missing = append(missing, m)
+	}
+
+	for _, r := range statScrums(ctx, store, reqs, getConcurrency()) {
+		obj, err := r.wait()
+		if err != nil {
+			log.Warn().Err(err).Str("username", r.user).Msg("unable to stat user's scrum")
+			continue
+		}
+
+		if status, away := leaveStatus(obj.Metadata, scrumDate); away {
+			missing = append(missing, &missingMember{teamMember: roster[r.user], status: status})
+		}
+	}
+
+	if err := findAbsences(store, scrumDate, forgot); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		forgotI, forgotJ := missing[i].status == missingStatusForgot, missing[j].status == missingStatusForgot
+		if forgotI != forgotJ {
+			return forgotI
+		}
+
+		return missing[i].User < missing[j].User
+	})
+
+	return missing, nil
+}
+
+// findAbsences looks for the most recent scrum of each member in the days
+// before scrumDate and updates the member's status when that scrum is a
+// vacation or sick leave that covers scrumDate.
+func findAbsences(store ScrumStore, scrumDate civilDate, members []*missingMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	ctx := cmdCtx
+
+	days := make([]civilDate, 0, missingLookbackDays)
+	for i := 1; i <= missingLookbackDays; i++ {
+		days = append(days, scrumDate.AddDate(0, 0, -i))
+	}
+
+	dayEntries, err := listScrumDays(ctx, store, days, getConcurrency())
+	if err != nil {
+		return err
+	}
+
+	// Days are searched from the most recent one.
+	lastScrum := make(map[string]civilDate, len(members))
+	for i, entries := range dayEntries {
+		for _, ent := range entries {
+			if _, found := lastScrum[ent.Name]; !found {
+				lastScrum[ent.Name] = days[i]
+			}
+		}
+	}
+
+	reqs := make([]scrumRequest, 0, len(members))
+	byUser := make(map[string]*missingMember, len(members))
+	for _, m := range members {
+		if date, found := lastScrum[m.User]; found {
+			reqs = append(reqs, scrumRequest{date: date, user: m.User})
+			byUser[m.User] = m
+		}
+	}
+
+	for _, r := range statScrums(ctx, store, reqs, getConcurrency()) {
+		obj, err := r.wait()
+		if err != nil {
+			log.Warn().Err(err).Str("username", r.user).Str("date", r.date.Format(dateInputFormat)).Msg("unable to stat user's last scrum")
+			continue
+		}
+
+		if status, away := leaveStatus(obj.Metadata, scrumDate); away {
+			byUser[r.user].status = status
+		}
+	}
+
+	return nil
+}
+
+// leaveStatus returns the status of a vacation or sick leave scrum, and
+// whether the leave covers scrumDate.
+func leaveStatus(md ScrumMetadata, scrumDate civilDate) (string, bool) {
+	if md.Status != scrumStatusVacation && md.Status != scrumStatusSick {
+		return "", false
+	}
+
+	if md.EndDate.IsZero() || md.EndDate.Before(scrumDate) {
+		return "", false
+	}
+
+	return md.Status + " until " + md.EndDate.Format(scrumMetadataDateLayout), true
+}
+
+// listMissing prints the members of roster who have not scrummed for
+// scrumDate.
+func listMissing(unbufOut io.Writer, store ScrumStore, roster teamRoster, scrumDate civilDate, manager string) error {
+	if !roster.works(scrumDate) {
+		log.Info().Str("date", scrumDate.Format(dateInputFormat)).Msgf("no scrums are expected on %ss", scrumDate.Weekday())
+		return nil
+	}
+
+	missing, err := findMissing(store, roster, scrumDate, manager)
+	if err != nil {
+		return err
+	}
+
+	if len(missing) == 0 {
+		log.Info().Str("date", scrumDate.Format(dateInputFormat)).Msg("everyone has scrummed")
+		return nil
+	}
+
+	w := bufio.NewWriter(unbufOut)
+	defer w.Flush()
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeaderLine(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+
+	table.SetHeader([]string{"name", "full name", "manager", "country", "status"})
+	if viper.GetBool(configKeyLogTermColor) {
+		table.SetHeaderColor(
+			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiWhiteColor},
+		)
+	}
+
+	var numForgot int
+	for _, m := range missing {
+		if m.status == missingStatusForgot {
+			numForgot++
+		}
+
+		table.Append([]string{m.User, m.Name, m.Manager, m.Country, m.status})
+	}
+	table.SetFooter([]string{"Missing", "", "", "", fmt.Sprintf("%d", numForgot)})
+
+	table.Render()
+
+	return nil
+}
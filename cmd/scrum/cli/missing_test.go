@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const testTeamRoster = `{
+  "alice": {"name": "Alice Smith", "country": "us", "manager": "dave"},
+  "bob": {"country": "uk", "manager": "dave"},
+  "carol": {"country": "us", "manager": "erin"},
+  "dave": {"country": "us"},
+  "frank": {"country": "us", "manager": "erin"}
+}`
+
+// missingLine returns the line of out listing user.
+func missingLine(out, user string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == user {
+			return line
+		}
+	}
+
+	return ""
+}
+
+func TestMissing(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/team.json", []byte(testTeamRoster))
+	env.mustRun("set", "-u", "carol", "-D", "2018-03-28", "-v", "5")
+	env.mustRun("set", "-u", "dave", "-D", "2018-03-30", "-s", "1")
+
+	// Older clients only wrote the first day of a vacation.
+	env.manta.DeleteObject("stor/scrum/2018/03/29/carol")
+	env.manta.DeleteObject("stor/scrum/2018/03/30/carol")
+	env.manta.PutObject("stor/scrum/2018/03/30/frank", []byte("did things\n"))
+
+	// Good Friday is a holiday in the UK but not in the US.
+	out := env.mustRun("missing", "-D", "2018-03-30")
+
+	for user, want := range map[string]string{
+		"alice": "missing",
+		"bob":   "holiday",
+		"carol": "vacation until 2018-04-03",
+		"dave":  "sick until 2018-03-30",
+	} {
+		if line := missingLine(out, user); !strings.Contains(line, want) {
+			t.Errorf("%s's line %q does not contain %q:\n%s", user, line, want, out)
+		}
+	}
+
+	if line := missingLine(out, "frank"); line != "" {
+		t.Errorf("frank scrummed but is listed:\n%s", out)
+	}
+
+	if strings.Index(out, "alice") > strings.Index(out, "bob") {
+		t.Errorf("members who forgot are not listed first:\n%s", out)
+	}
+}
+
+func TestMissingManagerAndTeamFile(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	teamFile := env.writeFile("team.json", testTeamRoster)
+
+	out := env.mustRun("missing", "--team-file", teamFile, "--manager", "erin")
+	for _, user := range []string{"carol", "frank"} {
+		if missingLine(out, user) == "" {
+			t.Errorf("%s is not listed:\n%s", user, out)
+		}
+	}
+
+	if missingLine(out, "alice") != "" {
+		t.Errorf("alice does not report to erin but is listed:\n%s", out)
+	}
+}
+
+func TestMissingWithoutRoster(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	if _, err := env.run("missing"); err == nil {
+		t.Errorf("missing without a team roster succeeded")
+	}
+}
+
+func TestMissingReadsSchedule(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyOOOFile, env.writeFile("ooo.json", testOOOSchedule))
+	defer viper.Set(configKeyOOOFile, nil)
+
+	env.manta.PutObject("stor/scrum/team.json", []byte(`{"alice": {}, "bob": {}}`))
+
+	out := env.mustRun("missing")
+	if line := missingLine(out, "bob"); !strings.Contains(line, "vacation until 2018-03-13") {
+		t.Errorf("bob's line %q does not contain his scheduled vacation:\n%s", line, out)
+	}
+
+	if line := missingLine(out, "alice"); !strings.Contains(line, "missing") {
+		t.Errorf("alice's line %q does not contain missing:\n%s", line, out)
+	}
+}
+
+func TestSetOnlyChecksLocalRoster(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/team.json", []byte(testTeamRoster))
+	env.manta.OnRequest(func(r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/team.json") {
+			t.Errorf("set fetched the team roster")
+		}
+	})
+
+	env.mustRun("set", "-u", "alice", "-i", env.writeFile("today.md", "did things\n"))
+}
@@ -0,0 +1,372 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanuber/columnize"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// oooMaxSkipDays bounds the number of days a user's days out of the office
+// are skipped when moving to the previous or next weekday.
+const oooMaxSkipDays = 60
+
+var oooCmd = &cobra.Command{
+	Args:         cobra.NoArgs,
+	Use:          "ooo",
+	Aliases:      []string{"out"},
+	Short:        "List who is out of the office",
+	SilenceUsage: true,
+	Long: `List who is out of the office today and this week.
+
+Days out of the office come from vacation and sick leave scrums and from the
+schedule file, ooo.file, which lists planned days out by user:
+
+  {
+    "alice": [{"start": "2018-03-12", "end": "2018-03-16", "status": "vacation"}]
+  }`,
+	Example: `  $ scrum ooo               # Who is out today and this week?
+  $ scrum ooo -D 2018-03-19 # Who is out next week?`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkRequiredFlags(cmd.Flags()); err != nil {
+			return errors.Wrap(err, "required flag missing")
+		}
+
+		return nil
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getScrumStore()
+		if err != nil {
+			return errors.Wrap(err, "unable to create a new scrum store")
+		}
+		defer dumpStoreStats(store)
+
+		date, err := getDateInLocation(viper.GetString(configKeyOOOInputDate))
+		if err != nil {
+			return errors.Wrap(err, "unable to parse date")
+		}
+
+		return listOOO(cmd.OutOrStdout(), store, date)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(oooCmd)
+
+	{
+		const (
+			key         = configKeyOOOInputDate
+			longName    = "date"
+			shortName   = "D"
+			description = "Date to check"
+		)
+		defaultValue := dateToday
+
+		flags := oooCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeyOOOFile
+			longName     = "schedule"
+			shortName    = ""
+			defaultValue = ""
+			description  = "Out of office schedule file"
+		)
+
+		flags := oooCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+}
+
+// oooPeriod is a span of days, inclusive, that a user is out of the office.
+type oooPeriod struct {
+	User   string
+	Status string
+	Start  civilDate
+	End    civilDate
+}
+
+// covers returns true if date is one of the days of the period.
+func (p *oooPeriod) covers(date civilDate) bool {
+	return !date.Before(p.Start) && !date.After(p.End)
+}
+
+// overlaps returns true if any day between since and until is one of the days
+// of the period.
+func (p *oooPeriod) overlaps(since, until civilDate) bool {
+	return !p.Start.After(until) && !since.After(p.End)
+}
+
+// days formats the days of the period.
+func (p *oooPeriod) days() string {
+	start, end := p.Start.Format(dateInputFormat), p.End.Format(dateInputFormat)
+	if start == end {
+		return start
+	}
+
+	return start + " to " + end
+}
+
+// oooCalendar holds every known period each user is out of the office.
+type oooCalendar struct {
+	periods map[string][]*oooPeriod
+}
+
+func newOOOCalendar() *oooCalendar {
+	return &oooCalendar{
+		periods: make(map[string][]*oooPeriod),
+	}
+}
+
+func (c *oooCalendar) add(p *oooPeriod) {
+	c.periods[p.User] = append(c.periods[p.User], p)
+}
+
+// lookup returns the period user is out of the office on date, if any.
+func (c *oooCalendar) lookup(user string, date civilDate) (*oooPeriod, bool) {
+	for _, p := range c.periods[user] {
+		if p.covers(date) {
+			return p, true
+		}
+	}
+
+	return nil, false
+}
+
+// overlapping returns every period between since and until sorted by user and
+// start date.
+func (c *oooCalendar) overlapping(since, until civilDate) []*oooPeriod {
+	var periods []*oooPeriod
+	for _, userPeriods := range c.periods {
+		for _, p := range userPeriods {
+			if p.overlaps(since, until) {
+				periods = append(periods, p)
+			}
+		}
+	}
+
+	sort.Slice(periods, func(i, j int) bool {
+		if periods[i].User != periods[j].User {
+			return periods[i].User < periods[j].User
+		}
+
+		return periods[i].Start.Before(periods[j].Start)
+	})
+
+	return periods
+}
+
+// loadOOOCalendar returns a calendar holding the periods of the schedule file,
+// ooo.file, if one is configured.
+func loadOOOCalendar() (*oooCalendar, error) {
+	cal := newOOOCalendar()
+
+	rawFilename := viper.GetString(configKeyOOOFile)
+	if rawFilename == "" {
+		return cal, nil
+	}
+
+	filename, err := homedir.Expand(rawFilename)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find a user's home directory")
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read out of office schedule")
+	}
+
+	var schedule map[string][]struct {
+		Start  string `json:"start"`
+		End    string `json:"end"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(buf, &schedule); err != nil {
+		return nil, errors.Wrap(err, "unable to parse out of office schedule")
+	}
+
+	for user, entries := range schedule {
+		for _, ent := range entries {
+			p := &oooPeriod{
+				User:   user,
+				Status: ent.Status,
+			}
+
+			if p.Start, err = parseCivilDate(dateInputFormat, ent.Start); err != nil {
+				return nil, errors.Wrapf(err, "invalid start date for %s in out of office schedule", user)
+			}
+
+			p.End = p.Start
+			if ent.End != "" {
+				if p.End, err = parseCivilDate(dateInputFormat, ent.End); err != nil {
+					return nil, errors.Wrapf(err, "invalid end date for %s in out of office schedule", user)
+				}
+			}
+
+			if p.Status == "" {
+				p.Status = scrumStatusVacation
+			}
+
+			cal.add(p)
+		}
+	}
+
+	return cal, nil
+}
+
+// addScrum adds the vacation or sick leave recorded by user's scrum on date
+// to the calendar.
+func (c *oooCalendar) addScrum(user string, date civilDate, md ScrumMetadata) (*oooPeriod, bool) {
+	if _, away := leaveStatus(md, date); !away {
+		return nil, false
+	}
+
+	p := &oooPeriod{
+		User:   user,
+		Status: md.Status,
+		Start:  date,
+		End:    md.EndDate,
+	}
+	c.add(p)
+
+	return p, true
+}
+
+// addScrums adds the vacations and sick leaves recorded by the scrums posted
+// on days to the calendar.  A day already covered by one of a user's periods
+// is not checked again, nor is a scrum too large to be a leave scrum.
+func (c *oooCalendar) addScrums(ctx context.Context, store ScrumStore, days []civilDate) error {
+	dayEntries, err := listScrumDays(ctx, store, days, getConcurrency())
+	if err != nil {
+		return err
+	}
+
+	for i, entries := range dayEntries {
+		var reqs []scrumRequest
+		for _, ent := range entries {
+			if v, found := usernameActionMap[ent.Name]; found && v == _Ignore {
+				continue
+			}
+
+			if ent.Size > maxLeaveScrumSize {
+				continue
+			}
+
+			if _, found := c.lookup(ent.Name, days[i]); !found {
+				reqs = append(reqs, scrumRequest{date: days[i], user: ent.Name})
+			}
+		}
+
+		for _, r := range statScrums(ctx, store, reqs, getConcurrency()) {
+			obj, err := r.wait()
+			if err != nil {
+				log.Warn().Err(err).Str("username", r.user).Str("date", r.date.Format(dateInputFormat)).Msg("unable to stat user's scrum")
+				continue
+			}
+
+			c.addScrum(r.user, r.date, obj.Metadata)
+		}
+	}
+
+	return nil
+}
+
+// getUserWeekday is like getWeekday but also skips the days user is out of
+// the office, according to cal or the user's vacation and sick leave scrums.
+func getUserWeekday(store ScrumStore, cal *oooCalendar, user string, date civilDate, nextDay bool) civilDate {
+	for i := 0; i < oooMaxSkipDays; i++ {
+		date = getWeekday(date, nextDay)
+
+		p, out := cal.lookup(user, date)
+		if !out {
+			ent, err := store.Stat(cmdCtx, date, user)
+			switch {
+			case err != nil && !isScrumNotFoundError(err):
+				log.Debug().Err(err).Str("username", user).Msg("unable to stat user's scrum")
+			case err == nil:
+				p, out = cal.addScrum(user, date, ent.Metadata)
+			}
+		}
+
+		if !out {
+			return date
+		}
+
+		log.Info().Str("username", user).Str("status", p.Status).Str("date", date.Format(dateInputFormat)).Msg("skipping day out of the office")
+	}
+
+	return date
+}
+
+// getWeek returns the first and last day of the scrum user's work week that
+// date falls in, e.g. the Monday and Friday of a "mon-fri" work week.
+func getWeek(date civilDate) (civilDate, civilDate) {
+	week := getMyWorkWeek()
+	first := week.weekOf(date)
+
+	last := first.AddDate(0, 0, 6)
+	for !week.has(last.Weekday()) {
+		last = last.AddDate(0, 0, -1)
+	}
+
+	return first, last
+}
+
+// listOOO prints who is out of the office on date and during date's week.
+func listOOO(unbufOut io.Writer, store ScrumStore, date civilDate) error {
+	cal, err := loadOOOCalendar()
+	if err != nil {
+		return err
+	}
+
+	first, last := getWeek(date)
+	if err := cal.addScrums(cmdCtx, store, getBusinessDays(first, last)); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(unbufOut)
+	defer w.Flush()
+
+	var today []string
+	for _, p := range cal.overlapping(date, date) {
+		today = append(today, fmt.Sprintf("%s | %s | until %s", p.User, p.Status, p.End.Format(dateInputFormat)))
+	}
+	writeOOOSection(w, fmt.Sprintf("Out today (%s):", date.Format("Monday 2006-01-02")), today)
+
+	w.WriteString("\n")
+
+	var week []string
+	for _, p := range cal.overlapping(first, last) {
+		week = append(week, fmt.Sprintf("%s | %s | %s", p.User, p.Status, p.days()))
+	}
+	writeOOOSection(w, fmt.Sprintf("Out this week (%s to %s):", first.Format(dateInputFormat), last.Format(dateInputFormat)), week)
+
+	return nil
+}
+
+func writeOOOSection(w io.Writer, title string, rows []string) {
+	fmt.Fprintln(w, title)
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "nobody")
+		return
+	}
+
+	fmt.Fprintln(w, columnize.SimpleFormat(rows))
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const testOOOSchedule = `{
+  "bob": [{"start": "2018-03-12", "end": "2018-03-13"}],
+  "erin": [{"start": "2018-03-15", "status": "sick"}]
+}`
+
+func TestOOO(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	schedule := env.writeFile("ooo.json", testOOOSchedule)
+	env.mustRun("set", "-u", "alice", "-D", "2018-03-09", "-v", "3")
+	env.mustRun("set", "-u", "carol", "-D", "2018-03-12", "-i", env.writeFile("carol", "did things\n"))
+
+	out := env.mustRun("ooo", "--schedule", schedule)
+
+	today := out[:strings.Index(out, "Out this week")]
+	for _, want := range []string{"Out today (Monday 2018-03-12)", "alice", "until 2018-03-13", "bob"} {
+		if !strings.Contains(today, want) {
+			t.Errorf("today's section does not contain %q:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(today, "erin") {
+		t.Errorf("erin is listed as out today:\n%s", out)
+	}
+
+	week := out[strings.Index(out, "Out this week"):]
+	for _, want := range []string{"2018-03-12 to 2018-03-16", "erin", "sick", "2018-03-15"} {
+		if !strings.Contains(week, want) {
+			t.Errorf("this week's section does not contain %q:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "carol") {
+		t.Errorf("carol scrummed but is listed as out:\n%s", out)
+	}
+}
+
+func TestOOONobody(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	out := env.mustRun("ooo", "-D", "2018-03-19")
+	if got, want := strings.Count(out, "nobody"), 2; got != want {
+		t.Errorf("%d sections list nobody, want %d:\n%s", got, want, out)
+	}
+}
+
+func TestGetYesterdaySkipsDaysOut(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	// alice was on vacation Thursday and Friday and is out today.
+	env.manta.PutObject("stor/scrum/2018/03/07/alice", []byte("wednesday\n"))
+	env.mustRun("set", "-u", "alice", "-D", "2018-03-08", "-v", "2")
+
+	out := env.mustRun("get", "-u", "alice", "-y")
+	if got, want := out, "wednesday\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	viper.Set(configKeyOOOFile, env.writeFile("ooo.json", testOOOSchedule))
+	defer viper.Set(configKeyOOOFile, nil)
+
+	env.manta.PutObject("stor/scrum/2018/03/14/bob", []byte("wednesday\n"))
+
+	out = env.mustRun("get", "-u", "bob", "-t", "-D", "2018-03-09")
+	if got, want := out, "wednesday\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestListAnnotatesDaysOut(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyOOOFile, env.writeFile("ooo.json", testOOOSchedule))
+	defer viper.Set(configKeyOOOFile, nil)
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("alice's scrum\n"))
+
+	out := env.mustRun("list")
+	if line := missingLine(out, "bob"); !strings.Contains(line, "vacation") || !strings.Contains(line, "2018-03-13") {
+		t.Errorf("bob's line %q is not annotated:\n%s", line, out)
+	}
+
+	if missingLine(out, "erin") != "" {
+		t.Errorf("erin is not out today but is listed:\n%s", out)
+	}
+}
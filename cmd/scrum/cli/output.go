@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// outputFormatDescription is the description of the -o flag of every command
+// with a machine-readable output.
+const outputFormatDescription = `Output format ("text", "json", "yaml", "csv" or "tsv")`
+
+type _OutputFormat uint
+
+const (
+	_OutputFormatText _OutputFormat = iota
+	_OutputFormatJSON
+	_OutputFormatYAML
+	_OutputFormatCSV
+	_OutputFormatTSV
+)
+
+func (f _OutputFormat) String() string {
+	switch f {
+	case _OutputFormatText:
+		return "text"
+	case _OutputFormatJSON:
+		return "json"
+	case _OutputFormatYAML:
+		return "yaml"
+	case _OutputFormatCSV:
+		return "csv"
+	case _OutputFormatTSV:
+		return "tsv"
+	default:
+		panic(fmt.Sprintf("unknown output format: %d", f))
+	}
+}
+
+// getOutputFormat returns the output format stored in the given configuration
+// key.
+func getOutputFormat(key string) (_OutputFormat, error) {
+	switch outputFormat := strings.ToLower(viper.GetString(key)); outputFormat {
+	case "", "text":
+		return _OutputFormatText, nil
+	case "json":
+		return _OutputFormatJSON, nil
+	case "yaml", "yml":
+		return _OutputFormatYAML, nil
+	case "csv":
+		return _OutputFormatCSV, nil
+	case "tsv":
+		return _OutputFormatTSV, nil
+	default:
+		return _OutputFormatText, errors.Errorf("unsupported output format: %q", outputFormat)
+	}
+}
+
+// scrumRecord is a single scrum in a machine-readable output format.  Body is
+// nil when only the scrum's metadata was fetched, e.g. by list, and is omitted
+// from the output.
+type scrumRecord struct {
+	User   string  `json:"user" yaml:"user"`
+	Date   string  `json:"date" yaml:"date"`
+	MTime  string  `json:"mtime,omitempty" yaml:"mtime,omitempty"`
+	Size   uint64  `json:"size" yaml:"size"`
+	ETag   string  `json:"etag,omitempty" yaml:"etag,omitempty"`
+	Status string  `json:"status" yaml:"status"`
+	Until  string  `json:"until,omitempty" yaml:"until,omitempty"`
+	Body   *string `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// newScrumRecord returns the record of user's scrum for date.  A scrum without
+// a status is a normal scrum.
+func newScrumRecord(date civilDate, obj *ScrumObject, includeBody bool) scrumRecord {
+	r := scrumRecord{
+		User:   obj.Name,
+		Date:   date.String(),
+		Size:   obj.Size,
+		ETag:   obj.ETag,
+		Status: obj.Metadata.Status,
+	}
+
+	if !obj.ModifiedTime.IsZero() {
+		r.MTime = displayTime(obj.ModifiedTime).Format(time.RFC3339)
+	}
+
+	if r.Status == "" {
+		r.Status = scrumStatusNormal
+	}
+
+	if !obj.Metadata.EndDate.IsZero() {
+		r.Until = obj.Metadata.EndDate.String()
+	}
+
+	if includeBody {
+		body := string(obj.Body)
+		r.Body = &body
+	}
+
+	return r
+}
+
+// tsvEscaper escapes the backslashes, tabs and line breaks of a TSV field.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writeScrumRecords writes records in format, which must not be
+// _OutputFormatText.  JSON and YAML are written as a list of records, CSV and
+// TSV as a header row followed by one row per record.  The body column is only
+// included if includeBody is true.
+func writeScrumRecords(w io.Writer, format _OutputFormat, records []scrumRecord, includeBody bool) error {
+	if records == nil {
+		records = []scrumRecord{}
+	}
+
+	switch format {
+	case _OutputFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(records); err != nil {
+			return errors.Wrap(err, "unable to encode scrums as JSON")
+		}
+
+		return nil
+	case _OutputFormatYAML:
+		buf, err := yaml.Marshal(records)
+		if err != nil {
+			return errors.Wrap(err, "unable to encode scrums as YAML")
+		}
+
+		_, err = w.Write(buf)
+		return err
+	case _OutputFormatCSV, _OutputFormatTSV:
+		header := []string{"user", "date", "mtime", "size", "etag", "status", "until"}
+		if includeBody {
+			header = append(header, "body")
+		}
+		rows := [][]string{header}
+
+		for _, r := range records {
+			row := []string{r.User, r.Date, r.MTime, fmt.Sprintf("%d", r.Size), r.ETag, r.Status, r.Until}
+			if includeBody {
+				var body string
+				if r.Body != nil {
+					body = *r.Body
+				}
+				row = append(row, body)
+			}
+			rows = append(rows, row)
+		}
+
+		if format == _OutputFormatTSV {
+			return writeTSV(w, rows)
+		}
+
+		if err := csv.NewWriter(w).WriteAll(rows); err != nil {
+			return errors.Wrap(err, "unable to write scrums as CSV")
+		}
+
+		return nil
+	default:
+		return errors.Errorf("unsupported output format: %q", format)
+	}
+}
+
+// writeTSV writes rows as tab separated values, one row per line.  Unlike CSV,
+// TSV has no quoting, so the backslashes, tabs and line breaks of a field are
+// escaped as "\\", "\t", "\n" and "\r".
+func writeTSV(unbufOut io.Writer, rows [][]string) error {
+	w := bufio.NewWriter(unbufOut)
+	for _, row := range rows {
+		for i, field := range row {
+			if i > 0 {
+				w.WriteByte('\t')
+			}
+			w.WriteString(tsvEscaper.Replace(field))
+		}
+		w.WriteByte('\n')
+	}
+
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "unable to write scrums as TSV")
+	}
+
+	return nil
+}
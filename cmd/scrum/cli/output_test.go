@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestGetOutputJSON(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("set", "-u", "alice", "-i", env.writeFile("today.md", "did things\n"))
+
+	var records []scrumRecord
+	if err := json.Unmarshal([]byte(env.mustRun("get", "-u", "alice", "-o", "json")), &records); err != nil {
+		t.Fatalf("unable to decode output: %v", err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("records = %+v, want 1 record", records)
+	}
+
+	r := records[0]
+	switch {
+	case r.User != "alice", r.Date != "2018-03-12", r.MTime != "2018-03-12T10:00:00Z", r.Status != scrumStatusNormal:
+		t.Errorf("record = %+v", r)
+	case r.Size != uint64(len("did things\n")), r.ETag == "":
+		t.Errorf("record = %+v, want the size and ETag of the scrum", r)
+	case r.Body == nil || *r.Body != "did things\n":
+		t.Errorf("record body = %v, want the scrum", r.Body)
+	}
+
+	if _, err := env.run("get", "-u", "bob", "-o", "json"); err == nil || !isScrumNotFoundError(err) {
+		t.Errorf("error = %v, want a missing scrum", err)
+	}
+
+	if _, err := env.run("get", "-o", "xml"); err == nil || !strings.Contains(err.Error(), "unsupported output format") {
+		t.Errorf("error = %v, want an unsupported output format", err)
+	}
+}
+
+func TestGetAllOutput(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/09/alice", []byte("friday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("monday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/bob", []byte("line one\nline two\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/all", []byte("everyone\n"))
+
+	var records []scrumRecord
+	if err := yaml.Unmarshal([]byte(env.mustRun("get", "-a", "-D", "last friday..today", "-o", "yaml")), &records); err != nil {
+		t.Fatalf("unable to decode output: %v", err)
+	}
+
+	var got []string
+	for _, r := range records {
+		got = append(got, r.Date+" "+r.User)
+	}
+	if want := []string{"2018-03-09 alice", "2018-03-12 alice", "2018-03-12 bob"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("records = %v, want %v", got, want)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(env.mustRun("get", "-a", "-o", "csv"))).ReadAll()
+	if err != nil {
+		t.Fatalf("unable to read csv output: %v", err)
+	}
+
+	if got, want := strings.Join(rows[0], ","), "user,date,mtime,size,etag,status,until,body"; got != want {
+		t.Errorf("csv header = %q, want %q", got, want)
+	}
+
+	if len(rows) != 3 || rows[2][0] != "bob" || rows[2][7] != "line one\nline two\n" {
+		t.Errorf("csv rows = %q", rows)
+	}
+}
+
+func TestGetOutputTSV(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("* TRITON-123\tdone\r\n* C:\\scrum\n"))
+
+	out := env.mustRun("get", "-a", "-o", "tsv")
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want a header and one record:\n%s", len(lines), out)
+	}
+
+	if got, want := lines[0], "user\tdate\tmtime\tsize\tetag\tstatus\tuntil\tbody"; got != want {
+		t.Errorf("header = %q, want %q", got, want)
+	}
+
+	fields := strings.Split(lines[1], "\t")
+	if len(fields) != 8 {
+		t.Fatalf("record has %d fields, want 8: %q", len(fields), lines[1])
+	}
+
+	if got, want := fields[7], `* TRITON-123\tdone\r\n* C:\\scrum\n`; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestListOutput(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyOOOFile, env.writeFile("ooo.json", testOOOSchedule))
+	defer viper.Set(configKeyOOOFile, nil)
+
+	env.mustRun("set", "-u", "alice", "-D", "2018-03-09", "-v", "3")
+	env.mustRun("set", "-u", "carol", "-i", env.writeFile("carol", "did things\n"))
+
+	var records []map[string]interface{}
+	if err := json.Unmarshal([]byte(env.mustRun("list", "-o", "json")), &records); err != nil {
+		t.Fatalf("unable to decode output: %v", err)
+	}
+
+	byUser := make(map[string]map[string]interface{}, len(records))
+	for _, r := range records {
+		if _, found := r["body"]; found {
+			t.Errorf("record %v includes the body", r)
+		}
+		byUser[r["user"].(string)] = r
+	}
+
+	for user, want := range map[string]string{"alice": scrumStatusVacation, "bob": scrumStatusVacation, "carol": scrumStatusNormal} {
+		if got := byUser[user]["status"]; got != want {
+			t.Errorf("%s's status = %v, want %s", user, got, want)
+		}
+	}
+
+	if got, want := byUser["alice"]["until"], "2018-03-13"; got != want {
+		t.Errorf("alice's until = %v, want %s", got, want)
+	}
+
+	if _, found := byUser["bob"]["mtime"]; found {
+		t.Errorf("bob has not scrummed but has an mtime: %v", byUser["bob"])
+	}
+
+	out := env.mustRun("list", "-o", "csv")
+	if !strings.HasPrefix(out, "user,date,mtime,size,etag,status,until\n") {
+		t.Errorf("output does not start with the CSV header:\n%s", out)
+	}
+
+	// Nobody has scrummed on Friday.
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"list", "-D", "2018-03-16", "-o", "json"}, "[]\n"},
+		{[]string{"list", "-D", "2018-03-16", "-o", "csv"}, "user,date,mtime,size,etag,status,until\n"},
+		{[]string{"get", "-a", "-D", "2018-03-16", "-o", "json"}, "[]\n"},
+	} {
+		if got := env.mustRun(test.args...); got != test.want {
+			t.Errorf("%v output = %q, want %q", test.args, got, test.want)
+		}
+	}
+}
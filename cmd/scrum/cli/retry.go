@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	tritonError "github.com/joyent/triton-go/errors"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// retryPolicy describes how a failed Manta request is retried.  The delay
+// before attempt n+1 doubles with every attempt starting at backoff, is capped
+// at maxBackoff, and is randomized between 50% and 100% of its value in order
+// to spread out retries from concurrent requests.
+type retryPolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// getRetryPolicy returns the retry policy from the current configuration.
+func getRetryPolicy() retryPolicy {
+	p := retryPolicy{
+		maxAttempts: viper.GetInt(configKeyMantaRetryAttempts),
+		backoff:     viper.GetDuration(configKeyMantaRetryBackoff),
+		maxBackoff:  viper.GetDuration(configKeyMantaRetryMaxBackoff),
+	}
+
+	if p.maxAttempts < 1 {
+		p.maxAttempts = 1
+	}
+
+	if p.maxBackoff < p.backoff {
+		p.maxBackoff = p.backoff
+	}
+
+	return p
+}
+
+// delay returns the randomized delay before the given retry (starting at 1).
+func (p retryPolicy) delay(retry int) time.Duration {
+	d := p.backoff
+	for i := 1; i < retry && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// do runs a single Manta request, fn, recording the latency of every attempt
+// against calls.  Every attempt is bounded by the Manta timeout.  Attempts
+// that fail with a retryable error are retried according to the retry policy
+// until ctx is done.
+func (sc *scrumClient) do(ctx context.Context, op, objectPath string, calls *uint64, fn func(ctx context.Context) error) error {
+	policy := getRetryPolicy()
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, viper.GetDuration(configKeyMantaTimeout))
+		start := time.Now()
+		err := fn(attemptCtx)
+		sc.recordCall(op, objectPath, start, calls)
+		cancel()
+
+		switch {
+		case err == nil:
+			return nil
+		case ctx.Err() != nil:
+			return errors.Wrap(ctx.Err(), op+" cancelled")
+		case attempt >= policy.maxAttempts || !isRetryableError(err):
+			return err
+		}
+
+		delay := policy.delay(attempt)
+		atomic.AddUint64(&sc.retries, 1)
+		log.Warn().Err(err).Str("op", op).Str("path", objectPath).Int("attempt", attempt).Str("delay", delay.String()).Msg("retrying manta request")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Wrap(ctx.Err(), op+" cancelled")
+		case <-timer.C:
+		}
+	}
+}
+
+// doOnce runs a single Manta request, fn, that is not safe to retry, e.g.
+// creating a job, recording its latency against calls.
+func (sc *scrumClient) doOnce(ctx context.Context, op, objectPath string, calls *uint64, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, viper.GetDuration(configKeyMantaTimeout))
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	sc.recordCall(op, objectPath, start, calls)
+
+	return err
+}
+
+// isRetryableError returns true when a failed Manta request may succeed if it
+// is tried again: server-side errors, throttling, timeouts and connections
+// that were reset.
+func isRetryableError(err error) bool {
+	switch {
+	case tritonError.IsInternalError(err),
+		tritonError.IsServiceUnavailableError(err),
+		tritonError.IsRequestThrottled(err),
+		tritonError.IsSpecificError(err, "ThrottledError"),
+		tritonError.IsConcurrentRequestError(err),
+		tritonError.IsUploadTimeoutError(err):
+		return true
+	}
+
+	for _, statusCode := range []int{
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	} {
+		if tritonError.IsSpecificStatusCode(err, statusCode) {
+			return true
+		}
+	}
+
+	cause := errors.Cause(err)
+	if urlErr, ok := cause.(*url.Error); ok {
+		cause = urlErr.Err
+	}
+
+	switch cause {
+	case context.DeadlineExceeded, io.EOF, io.ErrUnexpectedEOF:
+		return true
+	}
+
+	if netErr, ok := cause.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+
+	if opErr, ok := cause.(*net.OpError); ok {
+		cause = opErr.Err
+	}
+
+	if sysErr, ok := cause.(*os.SyscallError); ok {
+		cause = sysErr.Err
+	}
+
+	switch cause {
+	case syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.EPIPE:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetRetriesTransientErrors(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("did things\n"))
+	env.manta.FailRequests("stor/scrum/2018/03/12/alice", 2, http.StatusServiceUnavailable, "ServiceUnavailable")
+
+	out := env.mustRun("get", "-u", "alice", "--manta-retry-backoff", "1ms")
+	if got, want := out, "did things\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	if got, want := env.manta.Requests(http.MethodGet), uint64(3); got != want {
+		t.Errorf("GET requests = %d, want %d", got, want)
+	}
+}
+
+func TestGetGivesUpAfterMaxAttempts(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("did things\n"))
+	env.manta.FailRequests("stor/scrum/2018/03/12/alice", -1, http.StatusInternalServerError, "InternalError")
+
+	if _, err := env.run("get", "-u", "alice", "--manta-retry-attempts", "2", "--manta-retry-backoff", "1ms"); err == nil {
+		t.Fatalf("get succeeded")
+	}
+
+	if got, want := env.manta.Requests(http.MethodGet), uint64(2); got != want {
+		t.Errorf("GET requests = %d, want %d", got, want)
+	}
+}
+
+func TestGetDoesNotRetryNotFound(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	if _, err := env.run("get", "-u", "alice", "--manta-retry-backoff", "1ms"); err == nil {
+		t.Fatalf("get succeeded")
+	}
+
+	if got, want := env.manta.Requests(http.MethodGet), uint64(1); got != want {
+		t.Errorf("GET requests = %d, want %d", got, want)
+	}
+}
+
+func TestSetRetryAfterLostResponse(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	// The first put is applied but its response is lost, so the retry fails
+	// its If-None-Match precondition.
+	const objectPath = "stor/scrum/2018/03/12/alice"
+	env.manta.OnRequest(func(r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, objectPath) && env.manta.Requests(http.MethodPut) == 1 {
+			env.manta.PutObject(objectPath, []byte("did things\n"))
+			env.manta.FailRequests(objectPath, 1, http.StatusServiceUnavailable, "ServiceUnavailable")
+		}
+	})
+
+	input := env.writeFile("today.md", "did things\n")
+	env.mustRun("set", "-u", "alice", "-i", input, "--manta-retry-backoff", "1ms")
+
+	if got, want := env.manta.Requests(http.MethodPut), uint64(2); got != want {
+		t.Errorf("PUT requests = %d, want %d", got, want)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := retryPolicy{
+		maxAttempts: 10,
+		backoff:     100 * time.Millisecond,
+		maxBackoff:  time.Second,
+	}
+
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{8, 500 * time.Millisecond, time.Second},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 100; i++ {
+			if d := p.delay(test.retry); d < test.min || d > test.max {
+				t.Fatalf("delay(%d) = %s, want between %s and %s", test.retry, d, test.min, test.max)
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+
+	"github.com/gwydirsam/go-scrum/cmd/scrum/internal/buildtime"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// rollupTextName and rollupHTMLName are the names of the rollup objects
+	// stored alongside the day's scrums.  Both are hidden by
+	// usernameActionMap.
+	rollupTextName = "rollup"
+	rollupHTMLName = "all.html"
+)
+
+var rollupCmd = &cobra.Command{
+	Args:         cobra.NoArgs,
+	Use:          "rollup",
+	Short:        "Roll up scrums",
+	SilenceUsage: true,
+	Long: `Roll up every user's scrum for a day into a plain text "rollup" and an HTML
+"all.html", and store both in the day's scrum directory.
+
+The rollup only changes when a scrum does, so it is safe to rerun, e.g. after
+someone scrums late.`,
+	Example: `  $ scrum rollup               # Roll up today's scrums
+  $ scrum rollup -D 2018-03-12 # Roll up the scrums for March 12th`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkRequiredFlags(cmd.Flags()); err != nil {
+			return errors.Wrap(err, "required flag missing")
+		}
+
+		return nil
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getScrumStore()
+		if err != nil {
+			return errors.Wrap(err, "unable to create a new scrum store")
+		}
+		defer dumpStoreStats(store)
+
+		scrumDate, err := getDateInLocation(viper.GetString(configKeyRollupInputDate))
+		if err != nil {
+			return errors.Wrap(err, "unable to parse scrum date")
+		}
+
+		return rollupScrums(store, scrumDate)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollupCmd)
+
+	{
+		const (
+			key         = configKeyRollupInputDate
+			longName    = "date"
+			shortName   = "D"
+			description = "Date to roll up"
+		)
+		defaultValue := dateToday
+
+		flags := rollupCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+}
+
+// rollupScrums fetches every user's scrum for scrumDate and stores the text
+// and HTML rollups.  Nothing is stored unless every scrum was fetched.
+func rollupScrums(store ScrumStore, scrumDate civilDate) error {
+	ctx := cmdCtx
+	entries, err := store.ListDay(ctx, scrumDate)
+	if err != nil && !isScrumNotFoundError(err) {
+		return errors.Wrap(err, "unable to list scrum directory")
+	}
+
+	reqs := make([]scrumRequest, 0, len(entries))
+	for _, ent := range entries {
+		if v, found := usernameActionMap[ent.Name]; found && v == _Ignore {
+			continue
+		}
+
+		reqs = append(reqs, scrumRequest{date: scrumDate, user: ent.Name})
+	}
+
+	if len(reqs) == 0 {
+		log.Warn().Str("date", scrumDate.Format(dateInputFormat)).Msg("no users have scrummed for this day, nothing to roll up")
+		return nil
+	}
+
+	scrums := make([]*ScrumObject, 0, len(reqs))
+	for _, r := range fetchScrums(ctx, store, reqs, getConcurrency()) {
+		obj, err := r.wait()
+		if err != nil {
+			return errors.Wrapf(err, "unable to get %s's scrum", r.user)
+		}
+
+		scrums = append(scrums, obj)
+	}
+
+	text := formatRollupText(scrums)
+	html, err := formatRollupHTML(scrumDate, scrums)
+	if err != nil {
+		return err
+	}
+
+	if err := putRollup(store, scrumDate, rollupTextName, text); err != nil {
+		return err
+	}
+
+	return putRollup(store, scrumDate, rollupHTMLName, html)
+}
+
+// formatRollupText returns the plain text rollup of scrums.
+func formatRollupText(scrums []*ScrumObject) []byte {
+	var buf bytes.Buffer
+	for i, obj := range scrums {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+
+		fmt.Fprintf(&buf, "%s%s:\n", obj.Name, rollupStatus(obj.Metadata, " (%s)"))
+		buf.Write(bytes.TrimSpace(obj.Body))
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes()
+}
+
+// rollupStatus formats a scrum's status with format, or returns the empty
+// string for a normal scrum.
+func rollupStatus(md ScrumMetadata, format string) string {
+	if md.Status == "" || md.Status == scrumStatusNormal {
+		return ""
+	}
+
+	status := md.Status
+	if !md.EndDate.IsZero() {
+		status += " until " + md.EndDate.Format(scrumMetadataDateLayout)
+	}
+
+	return fmt.Sprintf(format, status)
+}
+
+var rollupHTMLTemplate = template.Must(template.New(rollupHTMLName).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Scrum for {{.Date}}</title>
+</head>
+<body>
+<h1>Scrum for {{.Date}}</h1>
+<ul>
+{{- range .Scrums}}
+<li><a href="#{{.User}}">{{.User}}</a></li>
+{{- end}}
+</ul>
+{{- range .Scrums}}
+<h2 id="{{.User}}"><a href="#{{.User}}">{{.User}}</a></h2>
+<p>Updated {{.MTime}}{{with .Status}} &middot; {{.}}{{end}}</p>
+<pre>{{.Body}}</pre>
+{{- end}}
+</body>
+</html>
+`))
+
+// formatRollupHTML returns the HTML rollup of scrums, with an anchor for
+// every user.
+func formatRollupHTML(scrumDate civilDate, scrums []*ScrumObject) ([]byte, error) {
+	type htmlScrum struct {
+		User   string
+		MTime  string
+		Status string
+		Body   string
+	}
+
+	data := struct {
+		Date   string
+		Scrums []htmlScrum
+	}{
+		Date: scrumDate.Format(dateInputFormat),
+	}
+
+	// Every mtime is in UTC so that teammates in different timezones render
+	// the same rollup.
+	for _, obj := range scrums {
+		mtime := obj.ModifiedTime.UTC()
+
+		data.Scrums = append(data.Scrums, htmlScrum{
+			User:   obj.Name,
+			MTime:  mtime.Format(mtimeFormatTZ),
+			Status: rollupStatus(obj.Metadata, "%s"),
+			Body:   string(bytes.TrimSpace(obj.Body)),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := rollupHTMLTemplate.Execute(&buf, data); err != nil {
+		return nil, errors.Wrap(err, "unable to render HTML rollup")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// putRollup stores a rollup object unless the stored copy is already up to
+// date.  The put is conditional on the copy that was compared, so concurrent
+// rollups can not overwrite each other with a stale rollup.
+func putRollup(store ScrumStore, scrumDate civilDate, name string, body []byte) error {
+	opts := PutOptions{
+		Metadata: ScrumMetadata{
+			Author:        scrumAuthor(),
+			ClientVersion: buildtime.Version,
+		},
+		IfNotExist: true,
+	}
+
+	existing, err := store.Get(cmdCtx, scrumDate, name)
+	switch {
+	case err == nil && bytes.Equal(existing.Body, body):
+		log.Info().Str("name", name).Msg("rollup is up to date")
+		return nil
+	case err == nil:
+		opts.IfNotExist = false
+		opts.IfMatch = existing.ETag
+	case !isScrumNotFoundError(err):
+		return errors.Wrapf(err, "unable to get existing %s", name)
+	}
+
+	return putScrum(store, scrumDate, name, bytes.NewReader(body), opts)
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRollup(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("did <things>\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/bob", []byte("  reviewed\n\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/all", []byte("old rollup\n"))
+
+	env.mustRun("rollup", "-D", "2018-03-12")
+
+	text, found := env.manta.Object("stor/scrum/2018/03/12/rollup")
+	if !found {
+		t.Fatalf("rollup was not stored")
+	}
+	if got, want := string(text), "alice:\ndid <things>\n\nbob:\nreviewed\n"; got != want {
+		t.Errorf("rollup = %q, want %q", got, want)
+	}
+
+	html, found := env.manta.Object("stor/scrum/2018/03/12/all.html")
+	if !found {
+		t.Fatalf("all.html was not stored")
+	}
+	for _, want := range []string{
+		`<a href="#alice">alice</a>`,
+		`<h2 id="bob">`,
+		`<pre>did &lt;things&gt;</pre>`,
+		"Updated 2018-03-12",
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("all.html does not contain %q:\n%s", want, html)
+		}
+	}
+
+	if strings.Contains(string(html), "old rollup") {
+		t.Errorf("all.html contains a reserved object:\n%s", html)
+	}
+}
+
+func TestRollupIsIdempotent(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("did things\n"))
+	env.mustRun("rollup", "-D", "2018-03-12")
+
+	puts := env.manta.Requests(http.MethodPut)
+	env.mustRun("rollup", "-D", "2018-03-12")
+	if got := env.manta.Requests(http.MethodPut); got != puts {
+		t.Errorf("rerunning an up to date rollup made %d PUT requests", got-puts)
+	}
+
+	// A teammate in another timezone renders the same rollup.
+	env.mustRun("rollup", "-D", "2018-03-12", "--tz", "America/Toronto")
+	if got := env.manta.Requests(http.MethodPut); got != puts {
+		t.Errorf("rerunning an up to date rollup in another timezone made %d PUT requests", got-puts)
+	}
+
+	// A late scrummer is added to the existing rollup.
+	env.manta.PutObject("stor/scrum/2018/03/12/bob", []byte("late\n"))
+	env.mustRun("rollup", "-D", "2018-03-12")
+
+	text, _ := env.manta.Object("stor/scrum/2018/03/12/rollup")
+	if got, want := string(text), "alice:\ndid things\n\nbob:\nlate\n"; got != want {
+		t.Errorf("rollup = %q, want %q", got, want)
+	}
+}
+
+func TestRollupWithoutScrums(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("rollup", "-D", "2018-03-12")
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/12/rollup"); found {
+		t.Errorf("rollup was stored for a day without scrums")
+	}
+}
@@ -1,11 +1,13 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	stdlog "log"
 	"os"
+	"os/signal"
 	"path"
 	"strings"
 	"time"
@@ -24,6 +26,10 @@ import (
 
 var stdLogger *stdlog.Logger
 
+// cmdCtx is the context for every request made by a command.  Execute cancels
+// cmdCtx when the user interrupts scrum.
+var cmdCtx = context.Background()
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:           "scrum",
@@ -41,6 +47,7 @@ var rootCmd = &cobra.Command{
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() error {
 	conswriter.UsePager(viper.GetBool(configKeyUsePager))
+	rootCmd.SetOutput(conswriter.GetTerminal())
 
 	logLevel, err := initLogLevels()
 	if err != nil {
@@ -84,7 +91,7 @@ func Execute() error {
 			}
 			zlog = zerolog.New(w).With().Timestamp().Logger()
 		default:
-			return fmt.Errorf("unsupported log format: %q")
+			return fmt.Errorf("unsupported log format: %q", logFmt)
 		}
 
 		log.Logger = zlog
@@ -99,6 +106,25 @@ func Execute() error {
 		}
 	}
 
+	// Cancel all in-flight requests on the first SIGINT.  A second SIGINT
+	// terminates the process.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmdCtx = ctx
+	{
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt)
+		go func() {
+			select {
+			case <-sigCh:
+				log.Warn().Msg("interrupted, cancelling requests")
+				signal.Stop(sigCh)
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
 	// Always enable the agent
 	if err := agent.Listen(agent.Options{}); err != nil {
 		log.Fatal().Err(err).Msg("unable to start gops agent")
@@ -147,13 +173,27 @@ func init() {
 	stdlog.SetFlags(0)
 	stdlog.SetOutput(zlog)
 
+	{
+		const (
+			key          = configKeyConcurrency
+			longOpt      = "concurrency"
+			shortOpt     = "j"
+			defaultValue = 8
+			description  = "Number of scrums to fetch in parallel"
+		)
+
+		flags := rootCmd.PersistentFlags()
+		flags.IntP(longOpt, shortOpt, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longOpt))
+	}
+
 	{
 		const (
 			key          = configKeyCountry
 			longOpt      = "country"
 			shortOpt     = "C"
 			defaultValue = "us"
-			description  = "Country holiday schedule"
+			description  = "Country holiday schedule, optionally with a region (e.g. \"ca-qc\")"
 		)
 
 		flags := rootCmd.PersistentFlags()
@@ -168,6 +208,24 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = configKeyWorkWeek
+			defaultValue = defaultWorkWeek
+		)
+
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeyDayStartsAt
+			defaultValue = "00:00"
+		)
+
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = configKeyLogLevel
@@ -250,6 +308,53 @@ func init() {
 		viper.BindEnv(key, "MANTA_KEY_ID")
 	}
 
+	{
+		const key = configKeyMantaKeyMaterial
+		const longOpt, shortOpt = "manta-key-material", ""
+		const defaultValue = ""
+		flags := rootCmd.PersistentFlags()
+		flags.StringP(longOpt, shortOpt, defaultValue, "SSH private key file (default is $MANTA_KEY_MATERIAL, or ssh-agent(1) if unset)")
+		viper.BindPFlag(key, flags.Lookup(longOpt))
+		viper.BindEnv(key, "MANTA_KEY_MATERIAL")
+	}
+
+	{
+		const (
+			key          = configKeyMantaRetryAttempts
+			longOpt      = "manta-retry-attempts"
+			shortOpt     = ""
+			description  = "Maximum number of attempts for a failed Manta request"
+			defaultValue = 4
+		)
+
+		flags := rootCmd.PersistentFlags()
+		flags.IntP(longOpt, shortOpt, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longOpt))
+	}
+
+	{
+		const (
+			key          = configKeyMantaRetryBackoff
+			longOpt      = "manta-retry-backoff"
+			shortOpt     = ""
+			description  = "Delay before retrying a failed Manta request, doubled after every attempt"
+			defaultValue = 250 * time.Millisecond
+		)
+
+		flags := rootCmd.PersistentFlags()
+		flags.DurationP(longOpt, shortOpt, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longOpt))
+	}
+
+	{
+		const (
+			key          = configKeyMantaRetryMaxBackoff
+			defaultValue = 4 * time.Second
+		)
+
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = configKeyMantaTimeout
@@ -293,6 +398,15 @@ func init() {
 		viper.BindEnv(key, "SCRUM_ACCOUNT")
 	}
 
+	{
+		const (
+			key          = configKeyStorageBackend
+			defaultValue = storageBackendManta
+		)
+
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = configKeyUsePager
@@ -332,6 +446,20 @@ func init() {
 		flags.BoolP(longName, shortName, defaultValue, description)
 		viper.BindPFlag(key, flags.Lookup(longName))
 	}
+
+	{
+		const (
+			key          = configKeyTimezone
+			longName     = "tz"
+			shortName    = ""
+			defaultValue = ""
+			description  = `Display times in a timezone, e.g. "America/Toronto" (overrides --utc)`
+		)
+
+		flags := rootCmd.PersistentFlags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+	}
 }
 
 func checkRequiredFlags(flags *pflag.FlagSet) error {
@@ -0,0 +1,336 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+	"github.com/gwydirsam/go-scrum/highlighter"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultSearchDays is the number of days before --until searched when
+// --since is not specified.
+const defaultSearchDays = 30
+
+var searchCmd = &cobra.Command{
+	Use:          "search query...",
+	SuggestFor:   []string{"find", "grep"},
+	Short:        "Search scrums",
+	SilenceUsage: true,
+	Long: `Search the scrums posted over a range of dates and print every matching
+line.  Each query is a word, matched like a highlight token:
+
+  keyword   case-insensitive exact match
+  keyword~  case-insensitive substring match
+  keyword~N match within a Damerau–Levenshtein distance of N
+
+A line matches when any of the queries match one of its words.
+
+With --remote, the scrums are searched by a Manta compute job instead of
+being downloaded, which is much faster when searching a long range of dates.
+The scrums of --users, or of everyone on the team roster, are searched
+without listing every day first.`,
+	Example: `  $ scrum search TRITON-123                      # Who mentioned TRITON-123 last month?
+  $ scrum search --since 2018-01-01 'manta~'     # Lines mentioning manta since January
+  $ scrum search --users alice,bob 'postgres~2'  # alice and bob's lines about postgres
+  $ scrum search --remote --since 2016-01-01 ZFS # Search years of scrums with a Manta job`,
+	Args: cobra.MinimumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkRequiredFlags(cmd.Flags()); err != nil {
+			return errors.Wrap(err, "required flag missing")
+		}
+
+		return nil
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		color.NoColor = !viper.GetBool(configKeyLogTermColor)
+
+		since, until, err := getDateRange(configKeySearchSince, configKeySearchUntil, defaultSearchDays)
+		if err != nil {
+			return err
+		}
+
+		toks := make([]*highlighter.TokenColor, 0, len(args))
+		for _, arg := range args {
+			tok, err := parseHighlightToken(arg)
+			if err != nil {
+				return errors.Wrapf(err, "unable to parse query %q", arg)
+			}
+
+			tok.Color = color.New(color.FgHiRed, color.Bold)
+			toks = append(toks, tok)
+		}
+
+		store, err := getScrumStore()
+		if err != nil {
+			return errors.Wrap(err, "unable to create a new scrum store")
+		}
+		defer dumpStoreStats(store)
+
+		var remote *scrumClient
+		if viper.GetBool(configKeySearchRemote) {
+			sc, ok := store.(*scrumClient)
+			if !ok {
+				return errors.Errorf("--remote requires the %q storage backend", storageBackendManta)
+			}
+			remote = sc
+		}
+
+		return searchScrums(cmd.OutOrStdout(), store, remote, getBusinessDays(since, until), getSearchUsers(), toks)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	{
+		const (
+			key          = configKeySearchRemote
+			longName     = "remote"
+			shortName    = ""
+			defaultValue = false
+			description  = "Search with a Manta compute job instead of downloading every scrum"
+		)
+
+		flags := searchCmd.Flags()
+		flags.BoolP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeySearchSince
+			longName     = "since"
+			shortName    = ""
+			defaultValue = ""
+			description  = "First date to search (defaults to 30 days before --until)"
+		)
+
+		flags := searchCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key         = configKeySearchUntil
+			longName    = "until"
+			shortName   = ""
+			description = "Last date to search"
+		)
+		defaultValue := dateToday
+
+		flags := searchCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key         = configKeySearchUsers
+			longName    = "users"
+			shortName   = ""
+			description = "Comma separated list of users to search (defaults to everyone)"
+		)
+		defaultValue := ""
+
+		flags := searchCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+}
+
+// getSearchUsers returns the users to search, either from a comma separated
+// flag or a list in the config file.
+func getSearchUsers() []string {
+	var users []string
+	for _, value := range viper.GetStringSlice(configKeySearchUsers) {
+		for _, user := range strings.Split(value, ",") {
+			if user = strings.TrimSpace(user); user != "" {
+				users = append(users, user)
+			}
+		}
+	}
+
+	return users
+}
+
+// searchWordRE matches the words of a line.
+var searchWordRE = regexp.MustCompile(`\S+`)
+
+// searchScrums fetches the scrums of users (or everyone) for each day in
+// parallel and prints every line that matches one of toks, in date and user
+// order, with the matching words highlighted.  When remote is not nil, the
+// scrums are searched by a Manta job run by remote instead of being fetched.
+func searchScrums(unbufOut io.Writer, store ScrumStore, remote *scrumClient, days []civilDate, users []string, toks []*highlighter.TokenColor) error {
+	ctx := cmdCtx
+
+	// A remote search of known users does not list every day: each user's
+	// scrum for every day is added to the job and the days without a scrum are
+	// skipped by the job.
+	if remote != nil && len(users) == 0 {
+		users = getRosterUsers(ctx, store)
+	}
+	guessInputs := remote != nil && len(users) > 0
+
+	var reqs []scrumRequest
+	if guessInputs {
+		for _, day := range days {
+			for _, user := range users {
+				if v, found := usernameActionMap[user]; found && v == _Ignore {
+					continue
+				}
+
+				reqs = append(reqs, scrumRequest{date: day, user: user})
+			}
+		}
+	} else {
+		if remote != nil {
+			log.Info().Msg("listing every day to find the scrums to search, set --users or a team roster to skip it")
+		}
+
+		var err error
+		reqs, err = listSearchRequests(ctx, store, days, users)
+		if err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(unbufOut)
+	defer w.Flush()
+
+	dateFmt := color.New(color.FgHiBlue).SprintFunc()
+	userFmt := color.New(color.FgHiWhite, color.Underline).SprintFunc()
+
+	var numErrors, numMatches int
+	printMatches := func(r scrumRequest, body []byte) {
+		lines := bufio.NewScanner(bytes.NewReader(body))
+		for lines.Scan() {
+			line, matched := highlightMatches(lines.Text(), toks)
+			if !matched {
+				continue
+			}
+
+			fmt.Fprintf(w, "%s %s: %s\n", dateFmt(r.date.Format(dateInputFormat)), userFmt(r.user), line)
+			numMatches++
+		}
+	}
+
+	if remote != nil {
+		if len(reqs) > 0 {
+			results, numFailures, err := searchRemote(ctx, remote, reqs, toks, guessInputs)
+			if err != nil {
+				return err
+			}
+			numErrors = numFailures
+
+			for _, r := range reqs {
+				if body, found := results[mantaScrumPath(r.date, r.user)]; found {
+					printMatches(r, body)
+				}
+			}
+		}
+	} else {
+		for _, r := range fetchScrums(ctx, store, reqs, getConcurrency()) {
+			obj, err := r.wait()
+			if err != nil {
+				log.Error().Err(err).Str("username", r.user).Str("date", r.date.Format(dateInputFormat)).Msg("unable to get user's scrum")
+				numErrors++
+				continue
+			}
+
+			printMatches(r.scrumRequest, obj.Body)
+		}
+	}
+
+	log.Debug().Int("matches", numMatches).Int("scrums", len(reqs)).Msg("search complete")
+
+	if numErrors > 0 {
+		return errors.Errorf("unable to search %d of %d scrums", numErrors, len(reqs))
+	}
+
+	return nil
+}
+
+// highlightMatches returns line with every word matching one of toks
+// highlighted, and whether any word matched.  Punctuation surrounding a word
+// is ignored when matching.
+func highlightMatches(line string, toks []*highlighter.TokenColor) (string, bool) {
+	var matched bool
+	line = searchWordRE.ReplaceAllStringFunc(line, func(word string) string {
+		trimmed := strings.TrimFunc(word, unicode.IsPunct)
+		for _, tok := range toks {
+			if tok.Match(word) || (trimmed != "" && tok.Match(trimmed)) {
+				matched = true
+				return tok.Color.Sprint(word)
+			}
+		}
+
+		return word
+	})
+
+	return strings.TrimSpace(line), matched
+}
+
+// listSearchRequests lists each day and returns the scrums of users (or
+// everyone) to search.
+func listSearchRequests(ctx context.Context, store ScrumStore, days []civilDate, users []string) ([]scrumRequest, error) {
+	dayEntries, err := listScrumDays(ctx, store, days, getConcurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	wantUser := make(map[string]bool, len(users))
+	for _, user := range users {
+		wantUser[user] = true
+	}
+
+	var reqs []scrumRequest
+	for i, entries := range dayEntries {
+		for _, ent := range entries {
+			if v, found := usernameActionMap[ent.Name]; found && v == _Ignore {
+				continue
+			}
+
+			if len(wantUser) > 0 && !wantUser[ent.Name] {
+				continue
+			}
+
+			reqs = append(reqs, scrumRequest{date: days[i], user: ent.Name})
+		}
+	}
+
+	return reqs, nil
+}
+
+// getRosterUsers returns the usernames on the team roster, or nothing when
+// there is no roster.
+func getRosterUsers(ctx context.Context, store ScrumStore) []string {
+	roster, err := loadTeamRoster(ctx, store)
+	if err != nil {
+		log.Debug().Err(err).Msg("unable to load team roster")
+		return nil
+	}
+
+	users := make([]string, 0, len(roster))
+	for _, member := range roster.members() {
+		users = append(users, member.User)
+	}
+
+	return users
+}
@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gwydirsam/go-scrum/highlighter"
+	"github.com/joyent/triton-go/client"
+	"github.com/joyent/triton-go/storage"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// searchJobName is the name of every remote search job.
+	searchJobName = "scrum-search"
+
+	// searchJobInputBatch is the maximum number of object paths added to a
+	// search job per request.
+	searchJobInputBatch = 1000
+
+	// searchJobPollInterval and searchJobMaxPollInterval bound the delay
+	// between polls of a search job's status.  The delay doubles after every
+	// poll.
+	searchJobPollInterval    = 250 * time.Millisecond
+	searchJobMaxPollInterval = 5 * time.Second
+)
+
+// searchJobFailure is a single entry of a job's error stream.
+type searchJobFailure struct {
+	Input string `json:"input"`
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// searchJobExec returns the exec of the map phase of a search job.  The phase
+// prints every line of its input object that may match one of toks, prefixed
+// with the object's path.  grep(1) cannot match words within a
+// Damerau–Levenshtein distance, so when any query is fuzzy every line is
+// printed.  The client matches the returned lines against toks in order to
+// discard lines grep(1) matched within a word.
+func searchJobExec(toks []*highlighter.TokenColor) string {
+	const prefix = `awk -v p="$MANTA_INPUT_OBJECT" '{ print p ":" $0 }'`
+
+	args := make([]string, 0, 2*len(toks))
+	for _, tok := range toks {
+		if !tok.Submatch && tok.Distance > 0 {
+			return prefix
+		}
+
+		args = append(args, "-e", shellQuote(tok.Token))
+	}
+
+	return "grep -i -F " + strings.Join(args, " ") + " | " + prefix
+}
+
+// shellQuote quotes s for use as a single word in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// searchRemote searches the scrums in reqs using a Manta compute job, which
+// saves downloading every scrum in order to search it.  The job's grep(1) map
+// phase selects the candidate lines and a reduce phase concatenates them into
+// a single output object.  searchRemote returns the candidate lines of every
+// scrum, keyed by the scrum's object path, and the number of scrums the job
+// failed to search, each of which is logged.  When ignoreMissing is true, reqs
+// may include scrums that do not exist, which are not failures.
+func searchRemote(ctx context.Context, sc *scrumClient, reqs []scrumRequest, toks []*highlighter.TokenColor, ignoreMissing bool) (map[string][]byte, int, error) {
+	jobs := sc.requestClient().Jobs()
+
+	var jobID string
+	err := sc.doOnce(ctx, "CreateJob", "jobs", &sc.jobCalls, func(ctx context.Context) error {
+		out, err := jobs.Create(ctx, &storage.CreateJobInput{
+			Name: searchJobName,
+			Phases: []*storage.JobPhase{
+				{Type: "map", Exec: searchJobExec(toks)},
+				{Type: "reduce", Exec: "cat"},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		jobID = out.JobID
+		return nil
+	})
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "unable to create search job")
+	}
+
+	log.Debug().Str("job", jobID).Int("scrums", len(reqs)).Msg("created search job")
+
+	inputs := make([]string, 0, len(reqs))
+	for _, r := range reqs {
+		inputs = append(inputs, sc.mantaObjectPath(mantaScrumPath(r.date, r.user)))
+	}
+
+	for len(inputs) > 0 {
+		batch := inputs
+		if len(batch) > searchJobInputBatch {
+			batch = batch[:searchJobInputBatch]
+		}
+		inputs = inputs[len(batch):]
+
+		err := sc.doOnce(ctx, "AddJobInputs", jobID, &sc.jobCalls, func(ctx context.Context) error {
+			return jobs.AddInputs(ctx, &storage.AddJobInputsInput{
+				JobID:       jobID,
+				ObjectPaths: batch,
+			})
+		})
+		if err != nil {
+			return nil, 0, errors.Wrapf(err, "unable to add inputs to search job %s", jobID)
+		}
+	}
+
+	err = sc.do(ctx, "EndJobInput", jobID, &sc.jobCalls, func(ctx context.Context) error {
+		return jobs.EndInput(ctx, &storage.EndJobInputInput{JobID: jobID})
+	})
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "unable to end input of search job %s", jobID)
+	}
+
+	if err := sc.waitForJob(ctx, jobID); err != nil {
+		return nil, 0, err
+	}
+
+	numFailures, err := sc.reportJobFailures(ctx, jobID, ignoreMissing)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	outputs, err := sc.readJobStream(ctx, jobID, "out")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	storPrefix := sc.mantaObjectPath("") + "/"
+	results := make(map[string][]byte)
+	for _, output := range outputs {
+		var lines []string
+		err := sc.do(ctx, "GetObject", output, &sc.getCalls, func(ctx context.Context) error {
+			respBody, _, err := sc.execute(ctx, http.MethodGet, strings.TrimPrefix(output, storPrefix))
+			if err != nil {
+				return err
+			}
+			defer respBody.Close()
+
+			lines, err = readLines(respBody)
+			return err
+		})
+		if err != nil {
+			return nil, 0, errors.Wrapf(err, "unable to get output %s of search job %s", output, jobID)
+		}
+
+		for _, line := range lines {
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			objectPath := strings.TrimPrefix(parts[0], storPrefix)
+			results[objectPath] = append(results[objectPath], parts[1]+"\n"...)
+		}
+	}
+
+	return results, numFailures, nil
+}
+
+// waitForJob polls the status of a job until it is done.  The job is
+// cancelled if ctx is done first, whether while waiting between polls or
+// during a poll.
+func (sc *scrumClient) waitForJob(ctx context.Context, jobID string) (err error) {
+	jobs := sc.requestClient().Jobs()
+
+	var done bool
+	defer func() {
+		if done || ctx.Err() == nil {
+			return
+		}
+
+		cancelCtx, cancel := context.WithTimeout(context.Background(), searchJobMaxPollInterval)
+		defer cancel()
+		if cancelErr := jobs.Cancel(cancelCtx, &storage.CancelJobInput{JobID: jobID}); cancelErr != nil {
+			log.Warn().Err(cancelErr).Str("job", jobID).Msg("unable to cancel search job")
+		}
+
+		err = errors.Wrapf(ctx.Err(), "search job %s cancelled", jobID)
+	}()
+
+	for delay := searchJobPollInterval; ; delay *= 2 {
+		var job *storage.Job
+		err := sc.do(ctx, "GetJob", jobID, &sc.jobCalls, func(ctx context.Context) error {
+			out, err := jobs.Get(ctx, &storage.GetJobInput{JobID: jobID})
+			if err != nil {
+				return err
+			}
+
+			job = out.Job
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to get status of search job %s", jobID)
+		}
+
+		if job.State == storage.JobStateDone {
+			done = true
+			return nil
+		}
+
+		if job.Stats != nil {
+			log.Debug().Str("job", jobID).Uint64("tasks", job.Stats.Tasks).Uint64("tasks-done", job.Stats.TasksDone).Msg("waiting for search job")
+		}
+
+		if delay > searchJobMaxPollInterval {
+			delay = searchJobMaxPollInterval
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// searchJobMissingInput is the code of the failure of a job's input that does
+// not exist.
+const searchJobMissingInput = "ResourceNotFoundError"
+
+// reportJobFailures logs every input a job failed to process and returns the
+// number of failures.  When ignoreMissing is true, inputs that do not exist
+// are not failures.
+func (sc *scrumClient) reportJobFailures(ctx context.Context, jobID string, ignoreMissing bool) (int, error) {
+	failures, err := sc.readJobStream(ctx, jobID, "fail")
+	if err != nil {
+		return 0, err
+	}
+
+	var numFailures int
+	for _, line := range failures {
+		var f searchJobFailure
+		if err := json.Unmarshal([]byte(line), &f); err != nil {
+			log.Warn().Err(err).Str("job", jobID).Str("failure", line).Msg("unable to parse job failure")
+			numFailures++
+			continue
+		}
+
+		if ignoreMissing && f.Error.Code == searchJobMissingInput {
+			log.Debug().Str("job", jobID).Str("path", f.Input).Msg("no scrum to search")
+			continue
+		}
+
+		log.Error().Str("job", jobID).Str("path", f.Input).Str("code", f.Error.Code).Msg(f.Error.Message)
+		numFailures++
+	}
+
+	return numFailures, nil
+}
+
+// readJobStream returns the lines of one of a job's live streams, "out" or
+// "fail".  Jobs().GetOutput and GetFailures are not used because they close
+// the stream before returning it.
+func (sc *scrumClient) readJobStream(ctx context.Context, jobID, stream string) ([]string, error) {
+	streamPath := path.Join("/", sc.StorageClient.Client.AccountName, "jobs", jobID, "live", stream)
+
+	var lines []string
+	err := sc.do(ctx, "GetJobStream", streamPath, &sc.jobCalls, func(ctx context.Context) error {
+		respBody, _, err := sc.requestClient().Client.ExecuteRequestStorage(ctx, client.RequestInput{
+			Method: http.MethodGet,
+			Path:   streamPath,
+		})
+		if err != nil {
+			return err
+		}
+		defer respBody.Close()
+
+		lines, err = readLines(respBody)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get %s of search job %s", stream, jobID)
+	}
+
+	// Every entry of a stream is an object name or a JSON object.
+	entries := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+
+	return entries, nil
+}
+
+// readLines returns the lines of r.  Only a trailing carriage return is
+// removed, the rest of each line, e.g. a scrum's indentation, is preserved.
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+
+	return lines, scanner.Err()
+}
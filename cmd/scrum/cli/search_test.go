@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func newSearchEnv(t *testing.T) *testEnv {
+	env := newTestEnv(t)
+
+	env.manta.PutObject("stor/scrum/2018/03/05/alice", []byte("Fixed TRITON-123.\nReviewed docs\n"))
+	env.manta.PutObject("stor/scrum/2018/03/07/bob", []byte("Looked at triton-123 with alice\nPostgres tuning\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("Postgress upgrade\n"))
+	env.manta.PutObject("stor/scrum/2018/03/12/rollup", []byte("TRITON-123\n"))
+
+	return env
+}
+
+func TestSearchExact(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	out := env.mustRun("search", "TRITON-123")
+	want := "2018-03-05 alice: Fixed TRITON-123.\n" +
+		"2018-03-07 bob: Looked at triton-123 with alice\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestSearchSubstringAndUsers(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	out := env.mustRun("search", "--users", "alice", "postgres~")
+	if got, want := out, "2018-03-12 alice: Postgress upgrade\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestSearchFuzzy(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	out := env.mustRun("search", "--since", "2018-03-06", "postgres~1")
+	for _, want := range []string{"bob: Postgres tuning", "alice: Postgress upgrade"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "TRITON") {
+		t.Errorf("output contains lines that do not match:\n%s", out)
+	}
+}
+
+func TestSearchRequiresQuery(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	if _, err := env.run("search"); err == nil {
+		t.Errorf("search without a query succeeded")
+	}
+}
+
+func TestSearchRemote(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	out := env.mustRun("search", "--remote", "TRITON-123")
+	want := "2018-03-05 alice: Fixed TRITON-123.\n" +
+		"2018-03-07 bob: Looked at triton-123 with alice\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	if got := env.manta.Requests(http.MethodPost); got == 0 {
+		t.Errorf("no search job was created")
+	}
+}
+
+func TestSearchRemoteDoesNotListKnownUsers(t *testing.T) {
+	for name, args := range map[string][]string{
+		"users":  {"search", "--remote", "--users", "alice,bob", "TRITON-123"},
+		"roster": {"search", "--remote", "TRITON-123"},
+	} {
+		env := newSearchEnv(t)
+		env.manta.PutObject("stor/scrum/team.json", []byte(`{"alice": {}, "bob": {}}`))
+
+		var lock sync.Mutex
+		var listed []string
+		env.manta.OnRequest(func(r *http.Request) {
+			if r.Method == http.MethodGet && searchDayDirRE.MatchString(r.URL.Path) {
+				lock.Lock()
+				listed = append(listed, r.URL.Path)
+				lock.Unlock()
+			}
+		})
+
+		out := env.mustRun(args...)
+		want := "2018-03-05 alice: Fixed TRITON-123.\n" +
+			"2018-03-07 bob: Looked at triton-123 with alice\n"
+		if out != want {
+			t.Errorf("%s: output = %q, want %q", name, out, want)
+		}
+
+		if len(listed) > 0 {
+			t.Errorf("%s: listed %d days, want none", name, len(listed))
+		}
+
+		env.Close()
+	}
+}
+
+// searchDayDirRE matches the path of a day's directory.
+var searchDayDirRE = regexp.MustCompile(`/stor/scrum/\d{4}/\d{2}/\d{2}$`)
+
+func TestSearchRemoteCancelsJob(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	sc := testStores(t, env)[storageBackendManta].(*scrumClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lock sync.Mutex
+	var cancelled bool
+	env.manta.OnRequest(func(r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/live/status"):
+			// The user interrupts scrum while it polls the job.
+			cancel()
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/live/cancel"):
+			lock.Lock()
+			cancelled = true
+			lock.Unlock()
+		}
+	})
+
+	if err := sc.waitForJob(ctx, "search-job"); err == nil {
+		t.Fatalf("wait for an interrupted job succeeded")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if !cancelled {
+		t.Errorf("interrupted job was not cancelled")
+	}
+}
+
+func TestSearchRemotePreservesIndentation(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/08/alice", []byte("* Reviews\r\n    - TRITON-123 follow up \r\n"))
+
+	local := env.mustRun("search", "--since", "2018-03-08", "TRITON-123")
+	if remote := env.mustRun("search", "--remote", "--since", "2018-03-08", "TRITON-123"); remote != local {
+		t.Errorf("remote output = %q, want the local output %q", remote, local)
+	}
+
+	lines, err := readLines(strings.NewReader("stor/a:    - nested \r\nstor/a:\n"))
+	if err != nil {
+		t.Fatalf("unable to read lines: %v", err)
+	}
+
+	if got, want := strings.Join(lines, "|"), "stor/a:    - nested |stor/a:"; got != want {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestSearchRemoteFuzzy(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	out := env.mustRun("search", "--remote", "--since", "2018-03-06", "postgres~1")
+	want := "2018-03-07 bob: Postgres tuning\n" +
+		"2018-03-12 alice: Postgress upgrade\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestSearchRemoteReportsFailures(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	// The job fails to read an object deleted after the scrums are listed.
+	env.manta.OnRequest(func(r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/live/in") {
+			env.manta.OnRequest(nil)
+			env.manta.DeleteObject("stor/scrum/2018/03/07/bob")
+		}
+	})
+
+	out, err := env.run("search", "--remote", "TRITON-123")
+	if err == nil {
+		t.Fatalf("search succeeded")
+	}
+
+	if want := "2018-03-05 alice: Fixed TRITON-123.\n"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestSearchRemoteRequiresManta(t *testing.T) {
+	env := newSearchEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyStorageBackend, storageBackendLocal)
+	viper.Set(configKeyStorageDirectory, env.dir)
+
+	if _, err := env.run("search", "--remote", "TRITON-123"); err == nil {
+		t.Errorf("remote search of a local store succeeded")
+	}
+}
@@ -1,15 +1,16 @@
 package cli
 
 import (
-	"context"
+	"bytes"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
-	tritonError "github.com/joyent/triton-go/errors"
-	"github.com/joyent/triton-go/storage"
+	"github.com/gwydirsam/go-scrum/cmd/scrum/internal/buildtime"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -23,7 +24,11 @@ var setCmd = &cobra.Command{
 	Long:         `Set scrum information, either for yourself (or teammates)`,
 	SilenceUsage: true,
 	Example: `  $ scrum set -i today.md                         # Set my scrum using today.md
-  $ scrum set -u other.username -t -i tomorrow.md # Set other.username's scrum for tomorrow`,
+  $ scrum set -u other.username -t -i tomorrow.md # Set other.username's scrum for tomorrow
+  $ scrum set -D yesterday -i yesterday.md        # Set my scrum for the previous weekday
+  $ scrum set -v 5                                # On vacation for the next 5 business days
+  $ scrum set --until 2018-03-23                  # On vacation until March 23rd
+  $ scrum set --leave sick --until 2018-03-14     # Sick leave until March 14th`,
 	Args: cobra.NoArgs,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		if err := checkRequiredFlags(cmd.Flags()); err != nil {
@@ -38,20 +43,14 @@ var setCmd = &cobra.Command{
 	},
 
 	RunE: func(cmd *cobra.Command, args []string) error {
-		//// validate username
-		//json, err := ioutil.ReadFile("team.json")
-		//if err != nil {
-		//	log.Fatalf("ioutil.ReadFile: %s", err)
-		//}
-		//result := gjson.GetBytes(json, userName)
-		//if result.String() == "" {
-		//	log.Fatalf("Expected Engineer")
-		//}
-		c, err := getScrumClient()
+		store, err := getScrumStore()
 		if err != nil {
-			return errors.Wrap(err, "unable to create a new scrum client")
+			return errors.Wrap(err, "unable to create a new scrum store")
 		}
-		defer c.dumpMantaClientStats()
+		defer dumpStoreStats(store)
+
+		// A username that is not on the team roster is usually a typo.
+		checkTeamMember(store, interpolateUserEnvVar(viper.GetString(configKeyScrumUsername)))
 
 		numDays := viper.GetInt(configKeySetNumDays)
 		if numDays < 1 {
@@ -74,37 +73,111 @@ var setCmd = &cobra.Command{
 			inputScrumDate = getPreviousWeekday(inputScrumDate)
 		}
 
-		// create end date string for vacation and sick time
-		endDate := inputScrumDate
-		if numSick > 0 || numVacation > 0 {
-			endDate = endDate.AddDate(0, 0, max(numSick, numVacation))
+		// A vacation or sick leave is N business days long or lasts until a given
+		// date.  --until is a vacation unless --leave says otherwise.
+		untilDate := viper.GetString(configKeySetUntil)
+		var leaveStatus string
+		switch leave := strings.ToLower(viper.GetString(configKeySetLeave)); {
+		case leave != "" && untilDate == "":
+			return errors.New("--leave requires --until")
+		case leave != "" && leave != scrumStatusVacation && leave != scrumStatusSick:
+			return errors.Errorf("unsupported leave %q (supported leaves: %s %s)", leave, scrumStatusSick, scrumStatusVacation)
+		case leave != "":
+			leaveStatus = leave
+		case numSick != 0:
+			leaveStatus = scrumStatusSick
+		case numVacation != 0 || untilDate != "":
+			leaveStatus = scrumStatusVacation
+		}
+
+		// The scrum is written for every business day from the input date: N
+		// days with --days, or every day of a leave.
+		var endDate civilDate
+		var scrumDates []civilDate
+		switch {
+		case leaveStatus != "" && viper.GetInt(configKeySetNumDays) > 0:
+			return errors.New("--days can not be combined with a vacation or sick leave")
+		case leaveStatus != "" && untilDate != "":
+			if numSick != 0 || numVacation != 0 {
+				return errors.New("--until can not be combined with a number of days")
+			}
+
+			endDate, err = getDateInLocation(untilDate)
+			if err != nil {
+				return errors.Wrap(err, "unable to parse until date")
+			}
+
+			scrumDates = getBusinessDays(inputScrumDate, endDate)
+		case leaveStatus != "":
+			endDate = addBusinessDays(inputScrumDate, max(numSick, numVacation))
+			scrumDates = getBusinessDays(inputScrumDate, endDate)
+		default:
+			scrumDates = append(scrumDates, inputScrumDate)
+			for len(scrumDates) < numDays {
+				scrumDates = append(scrumDates, getNextWeekday(scrumDates[len(scrumDates)-1]))
+			}
+		}
+
+		if len(scrumDates) == 0 {
+			return errors.Errorf("no business days between %s and %s", inputScrumDate.Format(dateInputFormat), endDate.Format(dateInputFormat))
 		}
 
+		metadata := ScrumMetadata{
+			Status:        scrumStatusNormal,
+			Author:        scrumAuthor(),
+			ClientVersion: buildtime.Version,
+		}
+
+		switch {
+		case leaveStatus != "":
+			metadata.Status = leaveStatus
+			metadata.EndDate = endDate
+		case viper.GetString(configKeySetFilename) != "-":
+			metadata.SourceFile = filepath.Base(viper.GetString(configKeySetFilename))
+		}
+
+		// The scrum is read once, before any day is written: stdin can only be
+		// read once and a conflict on one day must not change what is written
+		// on the next.
+		var body []byte
+		switch {
+		case viper.GetBool(configKeySetUnlinkDay):
+		case leaveStatus != "":
+			body = []byte(leaveScrumBody(leaveStatus, endDate))
+		default:
+			body, err = readScrumFile(viper.GetString(configKeySetFilename))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Once the scrum has been written for one day, every later day is linked
+		// to it rather than uploaded again.
+		var linkSource civilDate
+
 		var foundError bool
 	DAY_HANDLING:
-		for i := 0; i < numDays; i++ {
-			scrumDate := inputScrumDate.AddDate(0, 0, i)
+		for _, scrumDate := range scrumDates {
 
 			username := interpolateUserEnvVar(viper.GetString(configKeyScrumUsername))
-			scrumPath := path.Join("stor", "scrum", scrumDate.Format(scrumDateLayout), username)
-
-			// Check if scrum exists
-			ctx, _ := context.WithTimeout(context.Background(), viper.GetDuration(configKeyMantaTimeout))
-			start := time.Now()
-			_, err = c.Objects().Get(ctx, &storage.GetObjectInput{
-				ObjectPath: scrumPath,
-			})
-			elapsed := time.Now().Sub(start)
-			log.Debug().Str("path", scrumPath).Str("duration", elapsed.String()).Str("context", "pre-set").Msg("GetObject")
-			c.Histogram.RecordValue(float64(elapsed) / float64(time.Second))
-			c.getCalls++
+			scrumPath := path.Join(scrumDate.Format(scrumDateLayout), username)
+
+			// Check if scrum exists.  The put that follows is conditional on what
+			// was found here so that a scrum created or replaced in the meantime by
+			// another writer is never clobbered.
+			var ent *ScrumEntry
+			ent, err = store.Stat(cmdCtx, scrumDate, username)
+			putOpts := PutOptions{Metadata: metadata, IfNotExist: true}
 
 		ERROR_HANDLING:
 			switch {
-			case err != nil && tritonError.IsResourceNotFoundError(err):
+			case err != nil && isScrumNotFoundError(err) && viper.GetBool(configKeySetUnlinkDay):
+				log.Info().Str("path", scrumPath).Msg("no scrum to remove")
+				continue DAY_HANDLING
+			case err != nil && isScrumNotFoundError(err):
 				// User data doesn't exist
 				break ERROR_HANDLING
-			case err != nil && !tritonError.IsResourceNotFoundError(err):
+			case err != nil && !isScrumNotFoundError(err):
 				return errors.Wrap(err, "unknown error")
 			case err == nil:
 				// User data does exist
@@ -112,8 +185,8 @@ var setCmd = &cobra.Command{
 				// The semantic of "setting a DELETE" operation bugs me, but I don't
 				// want to expose a top-level command to delete my user file.
 				if viper.GetBool(configKeySetUnlinkDay) {
-					if err := unlinkObject(c, scrumPath); err != nil {
-						return errors.Wrap(err, "unable to unlink object")
+					if err := unlinkScrum(store, scrumDate, username, DeleteOptions{}); err != nil {
+						return errors.Wrap(err, "unable to unlink scrum")
 					}
 
 					continue DAY_HANDLING
@@ -123,17 +196,19 @@ var setCmd = &cobra.Command{
 					// If we're overriding multiple days, increase the verbosity of the
 					// log messages (vs the common case, overriding just today, in which
 					// case we just use the DEBUG level).
-					if numDays > 1 {
+					if len(scrumDates) > 1 {
 						log.Info().Str("path", scrumPath).Bool("force", viper.GetBool(configKeySetForce)).Msg("replacing scrum")
 					} else {
 						log.Debug().Str("path", scrumPath).Bool("force", viper.GetBool(configKeySetForce)).Msg("replacing scrum")
 					}
 
+					putOpts.IfNotExist = false
+					putOpts.IfMatch = ent.ETag
 					break ERROR_HANDLING
 				} else {
-					if numDays == 1 {
+					if len(scrumDates) == 1 {
 						log.Error().Str("path", scrumPath).Bool("force", viper.GetBool(configKeySetForce)).Msg("scrum exists, not replacing scrum without -f to override")
-						return errors.Wrap(err, "scrum already exists")
+						return errors.New("scrum already exists")
 					}
 
 					// Let users attempt to stamp out scrum for multiple days and skip over
@@ -147,41 +222,24 @@ var setCmd = &cobra.Command{
 				}
 			}
 
-			var reader io.Reader
-			switch {
-			case numSick != 0:
-				reader = strings.NewReader("Sick leave until " + endDate.Format(scrumDateLayout) + "\n")
-			case numVacation != 0:
-				reader = strings.NewReader("Vacation until " + endDate.Format(scrumDateLayout) + "\n")
-			case viper.GetString(configKeySetFilename) == "":
-				return errors.New("empty filename specified, use '-' as the input filename to use stdin")
-			case viper.GetString(configKeySetFilename) == "-":
-				reader = os.Stdin
-			case viper.GetString(configKeySetFilename) != "":
-				f, err := os.Open(viper.GetString(configKeySetFilename))
-				if err != nil {
-					return errors.Wrap(err, "unable to open(2) file")
-				}
-				defer f.Close()
-
-				sb, err := f.Stat()
-				if err != nil {
-					return errors.Wrap(err, "unable to stat(2) file")
-				}
-
-				if !sb.Mode().IsRegular() {
-					return errors.Wrapf(err, "%q is not a regular file", viper.GetString(configKeySetFilename))
-				}
+			if linkSource.IsZero() {
+				err = putScrum(store, scrumDate, username, bytes.NewReader(body), putOpts)
+			} else {
+				err = linkScrum(store, linkSource, scrumDate, username, LinkOptions{IfMatch: putOpts.IfMatch, IfNotExist: putOpts.IfNotExist})
+			}
 
-				if sb.Size() == 0 {
-					return errors.Errorf("scrum input is not a zero-byte file (%q)", viper.GetString(configKeySetFilename))
+			switch {
+			case err != nil && isScrumConflictError(err):
+				log.Error().Str("path", scrumPath).Bool("force", viper.GetBool(configKeySetForce)).Msg("scrum was changed by another writer, not replacing scrum")
+				if len(scrumDates) == 1 {
+					return errors.Wrapf(err, "unable to put scrum: %q", scrumPath)
 				}
 
-				reader = f
-			}
-
-			if err := putObject(c, scrumPath, reader); err != nil {
-				return errors.Wrapf(err, "unable to put object: %q", scrumPath)
+				foundError = true
+			case err != nil:
+				return errors.Wrapf(err, "unable to put scrum: %q", scrumPath)
+			case linkSource.IsZero():
+				linkSource = scrumDate
 			}
 		}
 
@@ -201,9 +259,9 @@ func init() {
 			key         = configKeySetInputDate
 			longName    = "date"
 			shortName   = "D"
-			description = "Date for scrum"
+			description = "Date for scrum (e.g. \"yesterday\" or \"last friday\")"
 		)
-		defaultValue := time.Now().Format(dateInputFormat)
+		defaultValue := dateToday
 
 		flags := setCmd.Flags()
 		flags.StringP(longName, shortName, defaultValue, description)
@@ -232,7 +290,7 @@ func init() {
 			longName     = "days"
 			shortName    = "d"
 			defaultValue = 0
-			description  = "Recycle scrum update for N days"
+			description  = "Recycle scrum update for N business days"
 		)
 
 		flags := setCmd.Flags()
@@ -247,7 +305,7 @@ func init() {
 			longName     = "sick"
 			shortName    = "s"
 			defaultValue = 0
-			description  = "Sick leave for N days"
+			description  = "Sick leave for N business days"
 		)
 
 		flags := setCmd.Flags()
@@ -256,6 +314,36 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = configKeySetUntil
+			longName     = "until"
+			shortName    = ""
+			defaultValue = ""
+			description  = "Last day of a vacation (or of the leave given by --leave)"
+		)
+
+		flags := setCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = configKeySetLeave
+			longName     = "leave"
+			shortName    = ""
+			defaultValue = ""
+			description  = `Kind of leave ending on --until ("vacation" or "sick")`
+		)
+
+		flags := setCmd.Flags()
+		flags.StringP(longName, shortName, defaultValue, description)
+		viper.BindPFlag(key, flags.Lookup(longName))
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key               = configKeySetTomorrow
@@ -274,7 +362,7 @@ func init() {
 			longName     = "vacation"
 			shortName    = "v"
 			defaultValue = 0
-			description  = "Vacation for N days"
+			description  = "Vacation for N business days"
 		)
 
 		flags := setCmd.Flags()
@@ -339,45 +427,96 @@ func max(a, b int) int {
 	return a
 }
 
-func putObject(c *scrumClient, scrumPath string, reader io.Reader) error {
-	putInput := &storage.PutObjectInput{
-		ObjectPath:   scrumPath,
-		ObjectReader: reader,
-		ForceInsert:  true,
+// scrumAuthor returns the user posting a scrum: the Manta user, or the local
+// user when no Manta user is configured.
+func scrumAuthor() string {
+	if author := interpolateMantaUserEnvVar(viper.GetString(configKeyMantaUser)); author != "" {
+		return author
+	}
+
+	return os.Getenv("USER")
+}
+
+// maxLeaveScrumSize is the size of the largest scrum written by
+// leaveScrumBody, a larger scrum is never a vacation or sick leave.
+var maxLeaveScrumSize = uint64(len(leaveScrumBody(scrumStatusSick, newCivilDate(9999, time.December, 31))))
+
+// leaveScrumBody returns the scrum posted for every day of a vacation or sick
+// leave that ends on endDate.
+func leaveScrumBody(status string, endDate civilDate) string {
+	if status == scrumStatusSick {
+		return "Sick leave until " + endDate.Format(scrumDateLayout) + "\n"
+	}
+
+	return "Vacation until " + endDate.Format(scrumDateLayout) + "\n"
+}
+
+// readScrumFile reads a scrum from filename, or from stdin when filename is
+// "-".  An empty scrum is rejected.
+func readScrumFile(filename string) ([]byte, error) {
+	var r io.Reader
+	switch filename {
+	case "":
+		return nil, errors.New("empty filename specified, use '-' as the input filename to use stdin")
+	case "-":
+		r = os.Stdin
+	default:
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to open(2) file")
+		}
+		defer f.Close()
+
+		sb, err := f.Stat()
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to stat(2) file")
+		}
+
+		if !sb.Mode().IsRegular() {
+			return nil, errors.Errorf("%q is not a regular file", filename)
+		}
+
+		r = f
 	}
 
-	ctx, _ := context.WithTimeout(context.Background(), viper.GetDuration(configKeyMantaTimeout))
-	defer func(start time.Time) {
-		elapsed := time.Now().Sub(start)
-		log.Debug().Str("path", scrumPath).Str("duration", elapsed.String()).Msg("PutObject")
-		c.Histogram.RecordValue(float64(elapsed) / float64(time.Second))
-		c.putCalls++
-	}(time.Now())
-	if err := c.Objects().Put(ctx, putInput); err != nil {
-		return errors.Wrap(err, "unable to put object")
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read scrum")
 	}
 
-	log.Info().Str("path", scrumPath).Msg("scrummed")
+	if len(body) == 0 {
+		return nil, errors.Errorf("scrum input is empty (%q)", filename)
+	}
+
+	return body, nil
+}
+
+func putScrum(store ScrumStore, scrumDate civilDate, user string, reader io.Reader, opts PutOptions) error {
+	if err := store.Put(cmdCtx, scrumDate, user, reader, opts); err != nil {
+		return errors.Wrap(err, "unable to put scrum")
+	}
+
+	log.Info().Str("path", path.Join(scrumDate.Format(scrumDateLayout), user)).Msg("scrummed")
 
 	return nil
 }
 
-func unlinkObject(c *scrumClient, scrumPath string) error {
-	deleteInput := &storage.DeleteObjectInput{
-		ObjectPath: scrumPath,
+func linkScrum(store ScrumStore, srcDate, dstDate civilDate, user string, opts LinkOptions) error {
+	if err := store.Link(cmdCtx, srcDate, dstDate, user, opts); err != nil {
+		return errors.Wrap(err, "unable to link scrum")
 	}
 
-	ctx, _ := context.WithTimeout(context.Background(), viper.GetDuration(configKeyMantaTimeout))
-	start := time.Now()
-	if err := c.Objects().Delete(ctx, deleteInput); err != nil {
-		return errors.Wrap(err, "unable to delete object")
+	log.Info().Str("path", path.Join(dstDate.Format(scrumDateLayout), user)).Str("source", path.Join(srcDate.Format(scrumDateLayout), user)).Msg("scrummed")
+
+	return nil
+}
+
+func unlinkScrum(store ScrumStore, scrumDate civilDate, user string, opts DeleteOptions) error {
+	if err := store.Delete(cmdCtx, scrumDate, user, opts); err != nil {
+		return errors.Wrap(err, "unable to delete scrum")
 	}
-	elapsed := time.Now().Sub(start)
-	log.Debug().Str("path", scrumPath).Str("duration", elapsed.String()).Msg("DeleteObject")
-	c.Histogram.RecordValue(float64(elapsed) / float64(time.Second))
-	c.deleteCalls++
 
-	log.Info().Str("path", scrumPath).Msg("removed scrum file")
+	log.Info().Str("path", path.Join(scrumDate.Format(scrumDateLayout), user)).Msg("removed scrum file")
 
 	return nil
 }
@@ -0,0 +1,398 @@
+package cli
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSetCreatesScrum(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	input := env.writeFile("today.md", "did things\n")
+	env.mustRun("set", "-u", "alice", "-i", input)
+
+	body, found := env.manta.Object("stor/scrum/2018/03/12/alice")
+	if !found {
+		t.Fatalf("scrum was not created")
+	}
+
+	if got, want := string(body), "did things\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestSetRefusesToReplace(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("original\n"))
+
+	input := env.writeFile("today.md", "replacement\n")
+	if _, err := env.run("set", "-u", "alice", "-i", input); err == nil {
+		t.Fatalf("set without -f replaced an existing scrum")
+	}
+
+	body, _ := env.manta.Object("stor/scrum/2018/03/12/alice")
+	if got, want := string(body), "original\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	env.mustRun("set", "-f", "-u", "alice", "-i", input)
+
+	body, _ = env.manta.Object("stor/scrum/2018/03/12/alice")
+	if got, want := string(body), "replacement\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestSetTomorrowSkipsWeekend(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	input := env.writeFile("tomorrow.md", "will do things\n")
+	env.mustRun("set", "-u", "alice", "-D", "2018-03-16", "-t", "-i", input)
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/19/alice"); !found {
+		t.Errorf("scrum was not created on the next weekday")
+	}
+}
+
+func TestSetVacation(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("set", "-u", "alice", "-v", "5")
+
+	body, found := env.manta.Object("stor/scrum/2018/03/12/alice")
+	if !found {
+		t.Fatalf("vacation scrum was not created")
+	}
+
+	if !strings.HasPrefix(string(body), "Vacation until 2018/03/16") {
+		t.Errorf("body = %q, want vacation notice", string(body))
+	}
+
+	for _, day := range []string{"13", "14", "15", "16"} {
+		if _, found := env.manta.Object("stor/scrum/2018/03/" + day + "/alice"); !found {
+			t.Errorf("vacation scrum was not created on 2018-03-%s", day)
+		}
+	}
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/19/alice"); found {
+		t.Errorf("vacation scrum was created after the last day of the vacation")
+	}
+}
+
+func TestSetVacationSkipsHolidays(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	// Christmas Eve, Christmas Day and the weekend are not business days.
+	env.mustRun("set", "-u", "alice", "-D", "2018-12-20", "-v", "3")
+
+	for _, date := range []string{"2018/12/20", "2018/12/21", "2018/12/26"} {
+		if _, found := env.manta.Object("stor/scrum/" + date + "/alice"); !found {
+			t.Errorf("vacation scrum was not created on %s", date)
+		}
+	}
+
+	for _, date := range []string{"2018/12/22", "2018/12/24", "2018/12/25"} {
+		if _, found := env.manta.Object("stor/scrum/" + date + "/alice"); found {
+			t.Errorf("vacation scrum was created on %s", date)
+		}
+	}
+
+	headers, _ := env.manta.ObjectHeaders("stor/scrum/2018/12/20/alice")
+	if got, want := headers.Get("m-scrum-end-date"), "2018-12-26"; got != want {
+		t.Errorf("m-scrum-end-date = %q, want %q", got, want)
+	}
+}
+
+func TestSetSickUntil(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("set", "-u", "alice", "--leave", "sick", "--until", "2018-03-19")
+
+	for _, day := range []string{"12", "13", "14", "15", "16", "19"} {
+		headers, found := env.manta.ObjectHeaders("stor/scrum/2018/03/" + day + "/alice")
+		if !found {
+			t.Errorf("sick leave scrum was not created on 2018-03-%s", day)
+			continue
+		}
+
+		if got, want := headers.Get("m-scrum-status"), scrumStatusSick; got != want {
+			t.Errorf("2018-03-%s: m-scrum-status = %q, want %q", day, got, want)
+		}
+	}
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/17/alice"); found {
+		t.Errorf("sick leave scrum was created on a weekend")
+	}
+}
+
+func TestSetUntilConflicts(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	if _, err := env.run("set", "-u", "alice", "-v", "2", "--until", "2018-03-19"); err == nil {
+		t.Errorf("set with both a number of days and --until succeeded")
+	}
+
+	if _, err := env.run("set", "-u", "alice", "-v", "2", "-d", "3"); err == nil {
+		t.Errorf("set with both a vacation and --days succeeded")
+	}
+
+	if _, err := env.run("set", "-u", "alice", "--leave", "sick"); err == nil {
+		t.Errorf("set with --leave but without --until succeeded")
+	}
+
+	if _, err := env.run("set", "-u", "alice", "--leave", "holiday", "--until", "2018-03-19"); err == nil {
+		t.Errorf("set with an unsupported leave succeeded")
+	}
+
+	if _, err := env.run("set", "-u", "alice", "--leave", "sick", "-s", "2", "--until", "2018-03-19"); err == nil {
+		t.Errorf("set with both --leave and a number of sick days succeeded")
+	}
+}
+
+func TestSetRemove(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("oops\n"))
+	env.mustRun("set", "--rm", "-u", "alice")
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/12/alice"); found {
+		t.Errorf("scrum was not removed")
+	}
+}
+
+func TestSetForceOnlyReplacesSeenScrum(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("original\n"))
+
+	// Replace the scrum after set has checked it, but before set replaces it.
+	env.manta.OnRequest(func(r *http.Request) {
+		if r.Method == http.MethodPut {
+			env.manta.OnRequest(nil)
+			env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("teammate's scrum\n"))
+		}
+	})
+
+	input := env.writeFile("today.md", "replacement\n")
+	if _, err := env.run("set", "-f", "-u", "alice", "-i", input); !isScrumConflictError(err) {
+		t.Fatalf("error = %v, want a conflict", err)
+	}
+
+	body, _ := env.manta.Object("stor/scrum/2018/03/12/alice")
+	if got, want := string(body), "teammate's scrum\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestSetCreateLosesRace(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.OnRequest(func(r *http.Request) {
+		if r.Method == http.MethodPut {
+			env.manta.OnRequest(nil)
+			env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("teammate's scrum\n"))
+		}
+	})
+
+	input := env.writeFile("today.md", "did things\n")
+	if _, err := env.run("set", "-u", "alice", "-i", input); !isScrumConflictError(err) {
+		t.Fatalf("error = %v, want a conflict", err)
+	}
+
+	body, _ := env.manta.Object("stor/scrum/2018/03/12/alice")
+	if got, want := string(body), "teammate's scrum\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestSetDaysLinksStdin(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	stdin, err := os.Open(env.writeFile("today.md", "recycled\n"))
+	if err != nil {
+		t.Fatalf("unable to open input: %v", err)
+	}
+	defer stdin.Close()
+
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = origStdin }()
+
+	// Create the day directories up front so that only scrum puts are counted.
+	for _, day := range []string{"12", "13", "14"} {
+		env.manta.PutDirectory("stor/scrum/2018/03/" + day)
+	}
+
+	var lock sync.Mutex
+	contentTypes := map[string]int{}
+	env.manta.OnRequest(func(r *http.Request) {
+		if r.Method == http.MethodPut {
+			lock.Lock()
+			contentTypes[r.Header.Get("Content-Type")]++
+			lock.Unlock()
+		}
+	})
+
+	env.mustRun("set", "-u", "alice", "-d", "3", "-i", "-")
+
+	for _, day := range []string{"12", "13", "14"} {
+		body, found := env.manta.Object("stor/scrum/2018/03/" + day + "/alice")
+		if got, want := string(body), "recycled\n"; !found || got != want {
+			t.Errorf("2018/03/%s: body = %q, want %q", day, got, want)
+		}
+	}
+
+	if got, want := contentTypes[""], 1; got != want {
+		t.Errorf("object puts = %d, want %d", got, want)
+	}
+
+	if got, want := contentTypes["application/json; type=link"], 2; got != want {
+		t.Errorf("snaplink puts = %d, want %d", got, want)
+	}
+}
+
+func TestSetForceDaysOnlyReplacesSeenScrums(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	for _, day := range []string{"12", "13", "14"} {
+		env.manta.PutObject("stor/scrum/2018/03/"+day+"/alice", []byte("original\n"))
+	}
+
+	// Replace the second day after set has checked it, but before set links it.
+	env.manta.OnRequest(func(r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/13/alice") {
+			env.manta.OnRequest(nil)
+			env.manta.PutObject("stor/scrum/2018/03/13/alice", []byte("teammate's scrum\n"))
+		}
+	})
+
+	input := env.writeFile("today.md", "replacement\n")
+	if _, err := env.run("set", "-f", "-u", "alice", "-d", "3", "-i", input); err == nil {
+		t.Fatalf("set over a changed scrum succeeded")
+	}
+
+	for day, want := range map[string]string{"12": "replacement\n", "13": "teammate's scrum\n", "14": "replacement\n"} {
+		body, _ := env.manta.Object("stor/scrum/2018/03/" + day + "/alice")
+		if got := string(body); got != want {
+			t.Errorf("2018/03/%s: body = %q, want %q", day, got, want)
+		}
+	}
+}
+
+func TestSetDaysStdinAfterConflict(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	stdin, err := os.Open(env.writeFile("today.md", "recycled\n"))
+	if err != nil {
+		t.Fatalf("unable to open input: %v", err)
+	}
+	defer stdin.Close()
+
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = origStdin }()
+
+	// Create the first day after set has checked it, so that its put fails.
+	env.manta.OnRequest(func(r *http.Request) {
+		if r.Method == http.MethodPut {
+			env.manta.OnRequest(nil)
+			env.manta.PutObject("stor/scrum/2018/03/12/alice", []byte("teammate's scrum\n"))
+		}
+	})
+
+	if _, err := env.run("set", "-u", "alice", "-d", "3", "-i", "-"); err == nil {
+		t.Fatalf("set over a created scrum succeeded")
+	}
+
+	for day, want := range map[string]string{"12": "teammate's scrum\n", "13": "recycled\n", "14": "recycled\n"} {
+		body, _ := env.manta.Object("stor/scrum/2018/03/" + day + "/alice")
+		if got := string(body); got != want {
+			t.Errorf("2018/03/%s: body = %q, want %q", day, got, want)
+		}
+	}
+}
+
+func TestSetRejectsEmptyInput(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	input := env.writeFile("today.md", "")
+	if _, err := env.run("set", "-u", "alice", "-i", input); err == nil {
+		t.Fatalf("set of an empty scrum succeeded")
+	}
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/12/alice"); found {
+		t.Errorf("empty scrum was written")
+	}
+}
+
+func TestSetRecordsMetadata(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.mustRun("set", "-U", "bob", "-u", "alice", "-v", "5")
+
+	headers, found := env.manta.ObjectHeaders("stor/scrum/2018/03/12/alice")
+	if !found {
+		t.Fatalf("vacation scrum was not created")
+	}
+
+	for key, want := range map[string]string{
+		"m-scrum-status":   scrumStatusVacation,
+		"m-scrum-end-date": "2018-03-16",
+		"m-scrum-author":   "bob",
+	} {
+		if got := headers.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	out := env.mustRun("get", "-a")
+	for _, want := range []string{"vacation until 2018-03-16", "bob"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("get output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	out = env.mustRun("ls")
+	for _, want := range []string{"STATUS", "vacation", "2018-03-16", "bob"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("list output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSetDaysSkipsWeekends(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	input := env.writeFile("today.md", "did things\n")
+	env.mustRun("set", "-u", "alice", "-D", "2018-03-16", "-d", "2", "-i", input)
+
+	for _, date := range []string{"2018/03/16", "2018/03/19"} {
+		if _, found := env.manta.Object("stor/scrum/" + date + "/alice"); !found {
+			t.Errorf("scrum was not created on %s", date)
+		}
+	}
+
+	if _, found := env.manta.Object("stor/scrum/2018/03/17/alice"); found {
+		t.Errorf("scrum was created on a weekend")
+	}
+}
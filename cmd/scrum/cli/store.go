@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// ErrScrumNotFound is returned by a ScrumStore when the requested scrum (or
+// day) does not exist.  Backends may wrap this error, use
+// isScrumNotFoundError() to test for it.
+var ErrScrumNotFound = errors.New("scrum not found")
+
+// ErrScrumConflict is returned by a ScrumStore when the preconditions of a
+// write were not met because another writer created or replaced the scrum.
+// Backends may wrap this error, use isScrumConflictError() to test for it.
+var ErrScrumConflict = errors.New("scrum was changed by another writer")
+
+// Statuses recorded in ScrumMetadata.Status.
+const (
+	scrumStatusNormal   = "normal"
+	scrumStatusVacation = "vacation"
+	scrumStatusSick     = "sick"
+)
+
+// Names of the ScrumMetadata fields as stored by a backend.
+const (
+	scrumMetadataStatus        = "status"
+	scrumMetadataEndDate       = "end-date"
+	scrumMetadataAuthor        = "author"
+	scrumMetadataClientVersion = "client-version"
+	scrumMetadataSourceFile    = "source-file"
+
+	// scrumMetadataDateLayout is the layout of the end-date field.
+	scrumMetadataDateLayout = "2006-01-02"
+)
+
+// ScrumMetadata is the structured information stored alongside a scrum.  Every
+// field is optional, scrums written by older clients have no metadata at all.
+type ScrumMetadata struct {
+	// Status is one of scrumStatusNormal, scrumStatusVacation or
+	// scrumStatusSick.
+	Status string
+
+	// EndDate is the last day of a vacation or sick leave.
+	EndDate civilDate
+
+	// Author is the user who posted the scrum, which is not necessarily the
+	// user the scrum is for.
+	Author string
+
+	// ClientVersion is the version of scrum used to post the scrum.
+	ClientVersion string
+
+	// SourceFile is the base name of the file the scrum was read from.
+	SourceFile string
+}
+
+// fields returns the non-empty metadata fields keyed by their stored names.
+func (md ScrumMetadata) fields() map[string]string {
+	fields := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+
+	set(scrumMetadataStatus, md.Status)
+	if !md.EndDate.IsZero() {
+		set(scrumMetadataEndDate, md.EndDate.Format(scrumMetadataDateLayout))
+	}
+	set(scrumMetadataAuthor, md.Author)
+	set(scrumMetadataClientVersion, md.ClientVersion)
+	set(scrumMetadataSourceFile, md.SourceFile)
+
+	return fields
+}
+
+// scrumMetadataFromFields is the inverse of ScrumMetadata.fields.  get returns
+// the stored value of a field, or the empty string.  Malformed fields are
+// ignored because they were written by someone else.
+func scrumMetadataFromFields(get func(key string) string) ScrumMetadata {
+	md := ScrumMetadata{
+		Status:        get(scrumMetadataStatus),
+		Author:        get(scrumMetadataAuthor),
+		ClientVersion: get(scrumMetadataClientVersion),
+		SourceFile:    get(scrumMetadataSourceFile),
+	}
+
+	if endDate, err := parseCivilDate(scrumMetadataDateLayout, get(scrumMetadataEndDate)); err == nil {
+		md.EndDate = endDate
+	}
+
+	return md
+}
+
+// ScrumEntry describes a single user's scrum for a given day.
+type ScrumEntry struct {
+	Name         string
+	Size         uint64
+	ModifiedTime time.Time
+	ETag         string
+	Metadata     ScrumMetadata
+}
+
+// ScrumObject is a scrum entry and its contents.
+type ScrumObject struct {
+	ScrumEntry
+
+	Body []byte
+}
+
+// PutOptions are the preconditions and metadata for ScrumStore.Put.  The zero
+// value unconditionally replaces any existing scrum without any metadata.
+type PutOptions struct {
+	// Metadata is stored alongside the scrum.
+	Metadata ScrumMetadata
+
+	// IfMatch only replaces the scrum when the ETag of the existing scrum
+	// matches.
+	IfMatch string
+
+	// IfNotExist only creates the scrum when no scrum exists.
+	IfNotExist bool
+}
+
+// LinkOptions are the preconditions for ScrumStore.Link.  The zero value
+// unconditionally replaces any existing scrum.
+type LinkOptions struct {
+	// IfMatch only replaces the scrum when the ETag of the existing scrum
+	// matches.
+	IfMatch string
+
+	// IfNotExist only creates the scrum when no scrum exists.
+	IfNotExist bool
+}
+
+// DeleteOptions are the preconditions for ScrumStore.Delete.  The zero value
+// unconditionally removes the scrum.
+type DeleteOptions struct {
+	// IfMatch only removes the scrum when the ETag of the existing scrum
+	// matches.
+	IfMatch string
+}
+
+// ScrumStore is the interface used by the scrum commands to read and write
+// scrums.  A scrum is addressed by its day and the user who scrummed.  How a
+// given day and user map to a storage location is left to the backend.
+type ScrumStore interface {
+	// Get returns the scrum for user on scrumDate.
+	Get(ctx context.Context, scrumDate civilDate, user string) (*ScrumObject, error)
+
+	// Put writes the scrum for user on scrumDate subject to the preconditions
+	// in opts.  ErrScrumConflict is returned when a precondition fails.
+	Put(ctx context.Context, scrumDate civilDate, user string, r io.Reader, opts PutOptions) error
+
+	// Link makes user's scrum on dstDate the same scrum (including its
+	// metadata) as the one on srcDate without uploading it again, subject to
+	// the preconditions in opts on the scrum on dstDate.  ErrScrumConflict is
+	// returned when a precondition fails.
+	Link(ctx context.Context, srcDate, dstDate civilDate, user string, opts LinkOptions) error
+
+	// Delete removes the scrum for user on scrumDate if it satisfies the
+	// preconditions in opts.  ErrScrumConflict is returned when a precondition
+	// fails.
+	Delete(ctx context.Context, scrumDate civilDate, user string, opts DeleteOptions) error
+
+	// ListDay returns every scrum entry for scrumDate sorted by name.  The
+	// entries do not include metadata, use Stat for that.
+	ListDay(ctx context.Context, scrumDate civilDate) ([]*ScrumEntry, error)
+
+	// Stat returns the metadata for user's scrum on scrumDate without fetching
+	// the contents.
+	Stat(ctx context.Context, scrumDate civilDate, user string) (*ScrumEntry, error)
+
+	// GetFile returns the contents of name, a file shared by every user that
+	// is stored next to the scrums rather than in a day, e.g. the team roster.
+	GetFile(ctx context.Context, name string) ([]byte, error)
+}
+
+// scrumStoreFactory creates a new ScrumStore from the current configuration.
+type scrumStoreFactory func() (ScrumStore, error)
+
+var scrumStoreBackends = map[string]scrumStoreFactory{}
+
+// registerScrumStore makes a ScrumStore backend available by name to the
+// storage.backend configuration key.
+func registerScrumStore(name string, factory scrumStoreFactory) {
+	if _, found := scrumStoreBackends[name]; found {
+		panic("scrum store backend registered twice: " + name)
+	}
+
+	scrumStoreBackends[name] = factory
+}
+
+// scrumStoreNames returns the sorted names of all registered backends.
+func scrumStoreNames() []string {
+	names := make([]string, 0, len(scrumStoreBackends))
+	for name := range scrumStoreBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// getScrumStore returns the ScrumStore selected by storage.backend.
+func getScrumStore() (ScrumStore, error) {
+	backend := strings.ToLower(viper.GetString(configKeyStorageBackend))
+
+	factory, found := scrumStoreBackends[backend]
+	if !found {
+		return nil, errors.Errorf("unsupported storage backend %q (supported backends: %s)",
+			backend, strings.Join(scrumStoreNames(), " "))
+	}
+
+	store, err := factory()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create %q storage backend", backend)
+	}
+
+	return store, nil
+}
+
+// dumpStoreStats logs the statistics of a ScrumStore if the backend collects
+// any.
+func dumpStoreStats(store ScrumStore) {
+	type statsDumper interface {
+		dumpStats()
+	}
+
+	if sd, ok := store.(statsDumper); ok {
+		sd.dumpStats()
+	}
+}
+
+// isScrumNotFoundError returns true when err was caused by a missing scrum.
+func isScrumNotFoundError(err error) bool {
+	return errors.Cause(err) == ErrScrumNotFound
+}
+
+// isScrumConflictError returns true when err was caused by a failed Put
+// precondition.
+func isScrumConflictError(err error) bool {
+	return errors.Cause(err) == ErrScrumConflict
+}
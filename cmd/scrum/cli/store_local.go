@@ -0,0 +1,565 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	storageBackendLocal = "local"
+
+	// localLockName is the name of the lock file created in a day's directory
+	// while a scrum is written or removed.  Like every dotfile, it is skipped by
+	// ListDay.
+	localLockName = ".lock"
+
+	// localLockTimeout is how long to wait for another writer to release a
+	// day's lock before giving up.
+	localLockTimeout = 5 * time.Second
+)
+
+// localLink creates hard links, tests replace it to simulate filesystems
+// without hard links.
+var localLink = os.Link
+
+func init() {
+	registerScrumStore(storageBackendLocal, func() (ScrumStore, error) {
+		return newLocalStore(viper.GetString(configKeyStorageDirectory))
+	})
+}
+
+// localStore stores scrums in a local directory (or a network share) using the
+// same year/month/day/user tree used in Manta.
+type localStore struct {
+	root string
+}
+
+func newLocalStore(rawDir string) (*localStore, error) {
+	if rawDir == "" {
+		return nil, errors.Errorf("%s must be set when using the %q storage backend", configKeyStorageDirectory, storageBackendLocal)
+	}
+
+	dir, err := homedir.Expand(rawDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find a user's home directory")
+	}
+
+	sb, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to stat(2) storage directory")
+	}
+
+	if !sb.IsDir() {
+		return nil, errors.Errorf("%q is not a directory", dir)
+	}
+
+	return &localStore{
+		root: dir,
+	}, nil
+}
+
+// dayDir returns the directory holding every scrum for scrumDate.
+func (ls *localStore) dayDir(scrumDate civilDate) string {
+	return filepath.Join(ls.root, filepath.FromSlash(scrumDate.Format(scrumDateLayout)))
+}
+
+// scrumPath returns the filename of user's scrum for scrumDate.
+func (ls *localStore) scrumPath(scrumDate civilDate, user string) (string, error) {
+	if user == "" || user == "." || user == ".." || strings.ContainsAny(user, `/\`) {
+		return "", errors.Errorf("invalid username: %q", user)
+	}
+
+	return filepath.Join(ls.dayDir(scrumDate), user), nil
+}
+
+func (ls *localStore) Get(ctx context.Context, scrumDate civilDate, user string) (*ScrumObject, error) {
+	filename, err := ls.scrumPath(scrumDate, user)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, localError(err, "unable to open(2) scrum")
+	}
+	defer f.Close()
+
+	sb, err := f.Stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to stat(2) scrum")
+	}
+
+	if !sb.Mode().IsRegular() {
+		return nil, errors.Errorf("%q is not a regular file", filename)
+	}
+
+	body, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read scrum")
+	}
+
+	return &ScrumObject{
+		ScrumEntry: localEntryOf(filename, sb, body),
+		Body:       body,
+	}, nil
+}
+
+// lockDay takes the lock for the directory of a single day, dir, which
+// serializes writers across processes sharing the same storage directory.  A
+// lock file is used (rather than flock(2) or fcntl(2)) because it works on
+// every platform and on network shares.  The returned function releases the
+// lock.
+func (ls *localStore) lockDay(ctx context.Context, dir string) (func(), error) {
+	lockPath := filepath.Join(dir, localLockName)
+
+	ctx, cancel := context.WithTimeout(ctx, localLockTimeout)
+	defer cancel()
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, errors.Wrap(err, "unable to create lock file")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Errorf("unable to lock %q, remove it if no other scrum command is running", lockPath)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// checkPutOptions returns ErrScrumConflict when the current scrum, filename,
+// does not satisfy the preconditions in opts.  The day's lock must be held.
+func checkPutOptions(filename string, opts PutOptions) error {
+	if !opts.IfNotExist && opts.IfMatch == "" {
+		return nil
+	}
+
+	sb, err := os.Stat(filename)
+	switch {
+	case err != nil && !os.IsNotExist(err):
+		return errors.Wrap(err, "unable to stat(2) scrum")
+	case opts.IfNotExist && err == nil:
+		return errors.Wrap(ErrScrumConflict, "scrum exists")
+	case opts.IfMatch != "" && err != nil:
+		return errors.Wrap(ErrScrumConflict, "scrum was removed")
+	case opts.IfMatch != "":
+		ent, err := localEntry(filename, sb)
+		if err != nil {
+			return err
+		}
+
+		if ent.ETag != opts.IfMatch {
+			return errors.Wrap(ErrScrumConflict, "scrum was replaced")
+		}
+	}
+
+	return nil
+}
+
+// Put writes the scrum to a temporary file in the day's directory and renames
+// it in to place so that readers never observe a partially written scrum.  The
+// metadata is added to the scrum's metadata file before the rename, see
+// writeLocalMetadata.  The preconditions in opts are checked while holding the
+// day's lock.
+func (ls *localStore) Put(ctx context.Context, scrumDate civilDate, user string, r io.Reader, opts PutOptions) error {
+	filename, err := ls.scrumPath(scrumDate, user)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "unable to create scrum directory")
+	}
+
+	unlock, err := ls.lockDay(ctx, dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := checkPutOptions(filename, opts); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+user+".")
+	if err != nil {
+		return errors.Wrap(err, "unable to create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "unable to write scrum")
+	}
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "unable to chmod(2) scrum")
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "unable to close scrum")
+	}
+
+	if err := writeLocalMetadata(filename, localHashSum(h), opts.Metadata.fields()); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return errors.Wrap(err, "unable to rename(2) scrum")
+	}
+
+	log.Debug().Str("path", filename).Msg("wrote scrum")
+
+	return nil
+}
+
+// Link hard links the scrum on srcDate in to dstDate's directory, or copies it
+// on filesystems without hard links (e.g. SMB shares or across devices).  Like
+// Put, the link is created under a temporary name and renamed in to place and
+// the preconditions in opts are checked while holding the day's lock.
+func (ls *localStore) Link(ctx context.Context, srcDate, dstDate civilDate, user string, opts LinkOptions) error {
+	srcFilename, err := ls.scrumPath(srcDate, user)
+	if err != nil {
+		return err
+	}
+
+	dstFilename, err := ls.scrumPath(dstDate, user)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(dstFilename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "unable to create scrum directory")
+	}
+
+	unlock, err := ls.lockDay(ctx, dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := checkPutOptions(dstFilename, PutOptions{IfMatch: opts.IfMatch, IfNotExist: opts.IfNotExist}); err != nil {
+		return err
+	}
+
+	// The temporary name is only used while holding the day's lock.
+	tmpFilename := filepath.Join(dir, "."+user+".link")
+	os.Remove(tmpFilename)
+
+	if err := localLink(srcFilename, tmpFilename); err != nil {
+		log.Debug().Err(err).Str("path", srcFilename).Msg("unable to link(2) scrum, copying it")
+
+		if err := copyLocalFile(srcFilename, tmpFilename); err != nil {
+			os.Remove(tmpFilename)
+			return err
+		}
+	}
+	defer os.Remove(tmpFilename)
+
+	// The source may be replaced while it is linked, so its metadata is looked
+	// up by the contents that were linked.
+	body, err := ioutil.ReadFile(tmpFilename)
+	if err != nil {
+		return errors.Wrap(err, "unable to read linked scrum")
+	}
+
+	bodyHash := localBodyHash(body)
+	if err := writeLocalMetadata(dstFilename, bodyHash, readLocalMetadataVersions(srcFilename)[bodyHash]); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpFilename, dstFilename); err != nil {
+		return errors.Wrap(err, "unable to rename(2) scrum")
+	}
+
+	log.Debug().Str("path", dstFilename).Str("source", srcFilename).Msg("linked scrum")
+
+	return nil
+}
+
+// copyLocalFile copies the scrum in srcFilename to a new file, dstFilename.
+func copyLocalFile(srcFilename, dstFilename string) error {
+	src, err := os.Open(srcFilename)
+	if err != nil {
+		return localError(err, "unable to open(2) scrum")
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dstFilename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.Wrap(err, "unable to create scrum copy")
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return errors.Wrap(err, "unable to copy scrum")
+	}
+
+	if err := dst.Close(); err != nil {
+		return errors.Wrap(err, "unable to close scrum copy")
+	}
+
+	return nil
+}
+
+// Delete removes the scrum and its metadata.  The precondition in opts is
+// checked while holding the day's lock.
+func (ls *localStore) Delete(ctx context.Context, scrumDate civilDate, user string, opts DeleteOptions) error {
+	filename, err := ls.scrumPath(scrumDate, user)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := ls.lockDay(ctx, filepath.Dir(filename))
+	if err != nil {
+		return localError(err, "unable to lock scrum directory")
+	}
+	defer unlock()
+
+	if err := checkPutOptions(filename, PutOptions{IfMatch: opts.IfMatch}); err != nil {
+		return err
+	}
+
+	if err := os.Remove(filename); err != nil {
+		return localError(err, "unable to unlink(2) scrum")
+	}
+
+	if err := os.Remove(localMetadataPath(filename)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "unable to unlink(2) scrum metadata")
+	}
+
+	return nil
+}
+
+// ListDay only stat(2)s the day's scrums.  The ETags of the listing are made
+// from the size and mtime of each scrum, reading and hashing every scrum on a
+// network share is too slow for a listing.  Stat and Get return the ETags
+// used as preconditions.
+func (ls *localStore) ListDay(ctx context.Context, scrumDate civilDate) ([]*ScrumEntry, error) {
+	fileInfos, err := ioutil.ReadDir(ls.dayDir(scrumDate))
+	if err != nil {
+		return nil, localError(err, "unable to read scrum directory")
+	}
+
+	entries := make([]*ScrumEntry, 0, len(fileInfos))
+	for _, sb := range fileInfos {
+		// Skip temporary files from an in-flight Put and anything that isn't a
+		// plain file.
+		if strings.HasPrefix(sb.Name(), ".") || !sb.Mode().IsRegular() {
+			continue
+		}
+
+		entries = append(entries, &ScrumEntry{
+			Name:         sb.Name(),
+			Size:         uint64(sb.Size()),
+			ModifiedTime: sb.ModTime(),
+			ETag:         fmt.Sprintf("%x-%x", sb.Size(), sb.ModTime().UnixNano()),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+func (ls *localStore) Stat(ctx context.Context, scrumDate civilDate, user string) (*ScrumEntry, error) {
+	filename, err := ls.scrumPath(scrumDate, user)
+	if err != nil {
+		return nil, err
+	}
+
+	sb, err := os.Stat(filename)
+	if err != nil {
+		return nil, localError(err, "unable to stat(2) scrum")
+	}
+
+	ent, err := localEntry(filename, sb)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ent, nil
+}
+
+func (ls *localStore) GetFile(ctx context.Context, name string) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, errors.Errorf("invalid file name: %q", name)
+	}
+
+	body, err := ioutil.ReadFile(filepath.Join(ls.root, name))
+	if err != nil {
+		return nil, localError(err, "unable to read file")
+	}
+
+	return body, nil
+}
+
+// localMetadataPath returns the name of the file holding the metadata of the
+// scrum in filename.  Like every dotfile, it is skipped by ListDay.
+//
+// The metadata file maps the hash of a scrum's contents to the metadata of that
+// version of the scrum.  Put adds the new version before renaming the scrum in
+// to place and keeps the version it replaces, so a reader always finds the
+// metadata of the scrum it read even though the two files are not replaced
+// together.
+func localMetadataPath(filename string) string {
+	return filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".metadata")
+}
+
+// localHashSum returns the hex encoded sum of h, truncated to 16 bytes.
+func localHashSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// localBodyHash returns the hash of a scrum's contents used to look up its
+// metadata.
+func localBodyHash(body []byte) string {
+	h := sha256.New()
+	h.Write(body)
+	return localHashSum(h)
+}
+
+// readLocalMetadataVersions returns the metadata fields of every version of
+// the scrum in filename keyed by the hash of its contents.  A missing or
+// malformed metadata file results in no versions.
+func readLocalMetadataVersions(filename string) map[string]map[string]string {
+	versions := make(map[string]map[string]string)
+
+	buf, err := ioutil.ReadFile(localMetadataPath(filename))
+	switch {
+	case err != nil && !os.IsNotExist(err):
+		log.Debug().Err(err).Str("path", filename).Msg("unable to read scrum metadata")
+	case err == nil:
+		if err := json.Unmarshal(buf, &versions); err != nil {
+			log.Debug().Err(err).Str("path", filename).Msg("unable to parse scrum metadata")
+		}
+	}
+
+	return versions
+}
+
+// writeLocalMetadata atomically replaces the metadata file of the scrum in
+// filename with the metadata fields of the scrum about to be renamed in to
+// place, whose contents hash to bodyHash, and of the current scrum.  Empty
+// metadata is not stored.  The day's lock must be held.
+func writeLocalMetadata(filename, bodyHash string, fields map[string]string) error {
+	metadataPath := localMetadataPath(filename)
+
+	versions := make(map[string]map[string]string)
+	if current, err := ioutil.ReadFile(filename); err == nil {
+		currentHash := localBodyHash(current)
+		if currentFields := readLocalMetadataVersions(filename)[currentHash]; len(currentFields) > 0 {
+			versions[currentHash] = currentFields
+		}
+	}
+
+	if len(fields) > 0 {
+		versions[bodyHash] = fields
+	} else {
+		delete(versions, bodyHash)
+	}
+
+	if len(versions) == 0 {
+		if err := os.Remove(metadataPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "unable to unlink(2) scrum metadata")
+		}
+
+		return nil
+	}
+
+	buf, err := json.Marshal(versions)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode scrum metadata")
+	}
+
+	tmpFilename := metadataPath + ".tmp"
+	if err := ioutil.WriteFile(tmpFilename, buf, 0644); err != nil {
+		os.Remove(tmpFilename)
+		return errors.Wrap(err, "unable to write scrum metadata")
+	}
+
+	if err := os.Rename(tmpFilename, metadataPath); err != nil {
+		os.Remove(tmpFilename)
+		return errors.Wrap(err, "unable to rename(2) scrum metadata")
+	}
+
+	return nil
+}
+
+// localEntry reads the scrum in filename and converts its metadata, sb, into
+// a ScrumEntry.
+func localEntry(filename string, sb os.FileInfo) (ScrumEntry, error) {
+	body, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return ScrumEntry{}, localError(err, "unable to read scrum")
+	}
+
+	return localEntryOf(filename, sb, body), nil
+}
+
+// localEntryOf converts the metadata, sb, of the scrum in filename whose
+// contents are body into a ScrumEntry including the scrum's metadata.  The
+// ETag is a hash of the scrum and its metadata: mtimes are too coarse on some
+// filesystems (e.g. NFS or FAT) to tell apart two writes of the same size in
+// quick succession.
+func localEntryOf(filename string, sb os.FileInfo, body []byte) ScrumEntry {
+	fields := readLocalMetadataVersions(filename)[localBodyHash(body)]
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", len(body))
+	h.Write(body)
+	if len(fields) > 0 {
+		// Maps are encoded with sorted keys.
+		md, _ := json.Marshal(fields)
+		h.Write(md)
+	}
+
+	return ScrumEntry{
+		Name:         sb.Name(),
+		Size:         uint64(len(body)),
+		ModifiedTime: sb.ModTime(),
+		ETag:         localHashSum(h),
+		Metadata: scrumMetadataFromFields(func(key string) string {
+			return fields[key]
+		}),
+	}
+}
+
+// localError translates a missing file into ErrScrumNotFound and wraps every
+// other error with msg.
+func localError(err error, msg string) error {
+	if os.IsNotExist(errors.Cause(err)) {
+		return errors.Wrap(ErrScrumNotFound, err.Error())
+	}
+
+	return errors.Wrap(err, msg)
+}
@@ -0,0 +1,462 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/joyent/triton-go/client"
+	tritonError "github.com/joyent/triton-go/errors"
+	"github.com/joyent/triton-go/storage"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const storageBackendManta = "manta"
+
+func init() {
+	registerScrumStore(storageBackendManta, func() (ScrumStore, error) {
+		return getScrumClient()
+	})
+}
+
+// mantaScrumDir returns the Manta directory holding every scrum for scrumDate.
+func mantaScrumDir(scrumDate civilDate) string {
+	return path.Join("stor", "scrum", scrumDate.Format(scrumDateLayout))
+}
+
+// mantaScrumPath returns the Manta object path of user's scrum for scrumDate.
+func mantaScrumPath(scrumDate civilDate, user string) string {
+	return path.Join(mantaScrumDir(scrumDate), user)
+}
+
+// recordCall logs and records the latency of a single Manta API call.
+func (sc *scrumClient) recordCall(op, objectPath string, start time.Time, calls *uint64) {
+	elapsed := time.Now().Sub(start)
+	log.Debug().Str("path", objectPath).Str("duration", elapsed.String()).Msg(op)
+	sc.Histogram.RecordValue(float64(elapsed) / float64(time.Second))
+	atomic.AddUint64(calls, 1)
+}
+
+// requestClient returns a StorageClient for a single request.  The triton
+// client resets its request headers after every request, which races when the
+// client is shared between goroutines, so every request gets a shallow copy of
+// the client.  The copy shares the underlying http.Client and its connections.
+func (sc *scrumClient) requestClient() *storage.StorageClient {
+	c := *sc.StorageClient.Client
+	return &storage.StorageClient{Client: &c}
+}
+
+// dumpStats satisfies the interface used by dumpStoreStats.
+func (sc *scrumClient) dumpStats() {
+	sc.dumpMantaClientStats()
+}
+
+// mantaMetadataPrefix prefixes the name of every ScrumMetadata field stored as
+// Manta object metadata.
+const mantaMetadataPrefix = "m-scrum-"
+
+// mantaObjectPath returns the absolute path of objectPath in the scrum
+// account.
+func (sc *scrumClient) mantaObjectPath(objectPath string) string {
+	return path.Join("/", sc.StorageClient.Client.AccountName, objectPath)
+}
+
+// execute runs a single object request.  Objects().Get and GetInfo are not
+// used because they look for metadata using lower case header names, which
+// never match the canonical names returned by net/http, so the metadata of
+// an object is always missing from their output.
+func (sc *scrumClient) execute(ctx context.Context, method, objectPath string) (io.ReadCloser, http.Header, error) {
+	return sc.requestClient().Client.ExecuteRequestStorage(ctx, client.RequestInput{
+		Method: method,
+		Path:   sc.mantaObjectPath(objectPath),
+	})
+}
+
+// mantaScrumEntry converts the response headers of an object into a
+// ScrumEntry.
+func mantaScrumEntry(user string, headers http.Header) ScrumEntry {
+	ent := ScrumEntry{
+		Name: user,
+		ETag: headers.Get("Etag"),
+		Metadata: scrumMetadataFromFields(func(key string) string {
+			return headers.Get(mantaMetadataPrefix + key)
+		}),
+	}
+
+	if lastModified, err := time.Parse(time.RFC1123, headers.Get("Last-Modified")); err == nil {
+		ent.ModifiedTime = lastModified
+	}
+
+	if size, err := strconv.ParseUint(headers.Get("Content-Length"), 10, 64); err == nil {
+		ent.Size = size
+	}
+
+	return ent
+}
+
+func (sc *scrumClient) Get(ctx context.Context, scrumDate civilDate, user string) (*ScrumObject, error) {
+	objectPath := mantaScrumPath(scrumDate, user)
+
+	var obj *ScrumObject
+	err := sc.do(ctx, "GetObject", objectPath, &sc.getCalls, func(ctx context.Context) error {
+		respBody, respHeaders, err := sc.execute(ctx, http.MethodGet, objectPath)
+		if err != nil {
+			return errors.Wrap(err, "unable to get object")
+		}
+		defer respBody.Close()
+
+		body, err := ioutil.ReadAll(respBody)
+		if err != nil {
+			return errors.Wrap(err, "unable to read manta object")
+		}
+
+		obj = &ScrumObject{
+			ScrumEntry: mantaScrumEntry(user, respHeaders),
+			Body:       body,
+		}
+		obj.Size = uint64(len(body))
+
+		return nil
+	})
+	if err != nil {
+		return nil, mantaError(err, "unable to get manta object")
+	}
+
+	return obj, nil
+}
+
+// Put uploads the scrum.  The day's directory is only created when Manta
+// reports that it is missing, which avoids the extra HEAD and PUT requests
+// made by PutObjectInput.ForceInsert for every scrum after the first one of
+// the day.  The preconditions in opts are sent as If-Match and If-None-Match
+// headers so that Manta, not the client, decides whether the put wins.
+//
+// A retried conditional put fails its precondition when an earlier attempt was
+// applied but its response was lost.  The put is then successful if the scrum
+// is the one that was sent.
+func (sc *scrumClient) Put(ctx context.Context, scrumDate civilDate, user string, r io.Reader, opts PutOptions) error {
+	objectPath := mantaScrumPath(scrumDate, user)
+
+	// Buffer the scrum so that it can be resent after creating the directory
+	// or when retrying.
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errors.Wrap(err, "unable to read scrum")
+	}
+
+	headers := map[string]string{}
+	for key, value := range opts.Metadata.fields() {
+		headers[mantaMetadataPrefix+key] = value
+	}
+
+	if opts.IfNotExist {
+		headers["If-None-Match"] = "*"
+	}
+
+	var attempts int
+	put := func(ctx context.Context) error {
+		attempts++
+		return sc.requestClient().Objects().Put(ctx, &storage.PutObjectInput{
+			ObjectPath:   objectPath,
+			ObjectReader: bytes.NewReader(body),
+			IfMatch:      opts.IfMatch,
+			Headers:      headers,
+		})
+	}
+
+	err = sc.do(ctx, "PutObject", objectPath, &sc.putCalls, put)
+	if err != nil && tritonError.IsDirectoryDoesNotExistError(err) {
+		if err := sc.mkdirScrumDay(ctx, scrumDate); err != nil {
+			return err
+		}
+
+		err = sc.do(ctx, "PutObject", objectPath, &sc.putCalls, put)
+	}
+	if err != nil {
+		err = mantaError(err, "unable to put object")
+	}
+
+	if attempts > 1 && isScrumConflictError(err) && sc.hasScrum(ctx, scrumDate, user, body) {
+		log.Debug().Str("path", objectPath).Msg("retried put was already applied")
+		return nil
+	}
+
+	return err
+}
+
+// hasScrum returns true if user's scrum on scrumDate is body.
+func (sc *scrumClient) hasScrum(ctx context.Context, scrumDate civilDate, user string, body []byte) bool {
+	obj, err := sc.Get(ctx, scrumDate, user)
+	if err != nil {
+		log.Debug().Err(err).Str("username", user).Msg("unable to get scrum after a conflict")
+		return false
+	}
+
+	return bytes.Equal(obj.Body, body)
+}
+
+// Link creates a SnapLink from the scrum on srcDate to dstDate.  The
+// SnapLink is created with a PUT of its own rather than SnapLinks().Put, which
+// does not accept headers, so that the preconditions in opts are sent as
+// If-Match and If-None-Match headers.  Like Put, a retried conditional link
+// that fails its precondition is successful if dstDate already links to the
+// scrum on srcDate.
+func (sc *scrumClient) Link(ctx context.Context, srcDate, dstDate civilDate, user string, opts LinkOptions) error {
+	srcPath := mantaScrumPath(srcDate, user)
+	dstPath := mantaScrumPath(dstDate, user)
+
+	headers := &http.Header{}
+	headers.Set("Content-Type", "application/json; type=link")
+	headers.Set("Location", sc.mantaObjectPath(srcPath))
+	if opts.IfMatch != "" {
+		headers.Set("If-Match", opts.IfMatch)
+	}
+	if opts.IfNotExist {
+		headers.Set("If-None-Match", "*")
+	}
+
+	var attempts int
+	link := func(ctx context.Context) error {
+		attempts++
+		respBody, _, err := sc.requestClient().Client.ExecuteRequestStorage(ctx, client.RequestInput{
+			Method:  http.MethodPut,
+			Path:    sc.mantaObjectPath(dstPath),
+			Headers: headers,
+		})
+		if respBody != nil {
+			respBody.Close()
+		}
+
+		return errors.Wrap(err, "unable to put snaplink")
+	}
+
+	err := sc.do(ctx, "PutSnapLink", dstPath, &sc.snapLinkCalls, link)
+	if err != nil && tritonError.IsDirectoryDoesNotExistError(err) {
+		if err := sc.mkdirScrumDay(ctx, dstDate); err != nil {
+			return err
+		}
+
+		err = sc.do(ctx, "PutSnapLink", dstPath, &sc.snapLinkCalls, link)
+	}
+	if err != nil {
+		err = mantaError(err, "unable to put snaplink")
+	}
+
+	if attempts > 1 && isScrumConflictError(err) && sc.isLinked(ctx, srcDate, dstDate, user) {
+		log.Debug().Str("path", dstPath).Msg("retried snaplink was already applied")
+		return nil
+	}
+
+	return err
+}
+
+// isLinked returns true if user's scrum on dstDate is a SnapLink of the scrum
+// on srcDate.  A SnapLink shares the ETag of its source.
+func (sc *scrumClient) isLinked(ctx context.Context, srcDate, dstDate civilDate, user string) bool {
+	src, err := sc.Stat(ctx, srcDate, user)
+	if err != nil {
+		log.Debug().Err(err).Str("username", user).Msg("unable to stat scrum after a conflict")
+		return false
+	}
+
+	dst, err := sc.Stat(ctx, dstDate, user)
+	if err != nil {
+		log.Debug().Err(err).Str("username", user).Msg("unable to stat scrum after a conflict")
+		return false
+	}
+
+	return src.ETag != "" && src.ETag == dst.ETag
+}
+
+// mkdirScrumDay creates the year, month and day directories for scrumDate.
+func (sc *scrumClient) mkdirScrumDay(ctx context.Context, scrumDate civilDate) error {
+	dirPath := path.Join("stor", "scrum")
+	for _, layout := range []string{"2006", "01", "02"} {
+		dirPath = path.Join(dirPath, scrumDate.Format(layout))
+
+		err := sc.do(ctx, "PutDirectory", dirPath, &sc.putCalls, func(ctx context.Context) error {
+			return sc.requestClient().Dir().Put(ctx, &storage.PutDirectoryInput{
+				DirectoryName: dirPath,
+			})
+		})
+		if err != nil {
+			return errors.Wrap(err, "unable to create manta directory")
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the scrum.  The precondition in opts is sent as an If-Match
+// header.  A conditional delete is not retried: if the response to a delete
+// that succeeded was lost, the retry would fail with a not found error.
+func (sc *scrumClient) Delete(ctx context.Context, scrumDate civilDate, user string, opts DeleteOptions) error {
+	objectPath := mantaScrumPath(scrumDate, user)
+
+	headers := map[string]string{}
+	if opts.IfMatch != "" {
+		headers["If-Match"] = opts.IfMatch
+	}
+
+	del := func(ctx context.Context) error {
+		return sc.requestClient().Objects().Delete(ctx, &storage.DeleteObjectInput{
+			ObjectPath: objectPath,
+			Headers:    headers,
+		})
+	}
+
+	do := sc.do
+	if opts.IfMatch != "" {
+		do = sc.doOnce
+	}
+
+	err := do(ctx, "DeleteObject", objectPath, &sc.deleteCalls, del)
+	if err != nil {
+		return mantaError(err, "unable to delete object")
+	}
+
+	return nil
+}
+
+// mantaListLimit is the number of directory entries requested per page.  It
+// is the largest limit accepted by Manta.
+const mantaListLimit = 1024
+
+// ListDay pages through the day's directory until Manta returns a short page.
+// The marker is sent as "marker" rather than through ListDirectoryInput, which
+// sends it as "manta_path" where Manta ignores it.  Manta includes the marker
+// in the next page, so an entry named like the marker is skipped.
+func (sc *scrumClient) ListDay(ctx context.Context, scrumDate civilDate) ([]*ScrumEntry, error) {
+	scrumPath := mantaScrumDir(scrumDate)
+
+	var entries []*ScrumEntry
+	var marker string
+	for {
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(mantaListLimit))
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		var page []*storage.DirectoryEntry
+		err := sc.do(ctx, "ListDirectory", scrumPath, &sc.listCalls, func(ctx context.Context) error {
+			respBody, _, err := sc.requestClient().Client.ExecuteRequestStorage(ctx, client.RequestInput{
+				Method: http.MethodGet,
+				Path:   sc.mantaObjectPath(scrumPath),
+				Query:  &query,
+			})
+			if err != nil {
+				return errors.Wrap(err, "unable to list directory")
+			}
+			defer respBody.Close()
+
+			page = page[:0]
+			dec := json.NewDecoder(respBody)
+			for dec.More() {
+				ent := &storage.DirectoryEntry{}
+				if err := dec.Decode(ent); err != nil {
+					return errors.Wrap(err, "unable to decode list directories response")
+				}
+				page = append(page, ent)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, mantaError(err, "unable to list manta directory")
+		}
+
+		var added int
+		for _, ent := range page {
+			if marker != "" && ent.Name == marker {
+				continue
+			}
+
+			entries = append(entries, &ScrumEntry{
+				Name:         ent.Name,
+				Size:         ent.Size,
+				ModifiedTime: ent.ModifiedTime,
+				ETag:         ent.ETag,
+			})
+			added++
+		}
+
+		if len(page) < mantaListLimit || added == 0 {
+			break
+		}
+
+		marker = page[len(page)-1].Name
+	}
+
+	if entries == nil {
+		entries = []*ScrumEntry{}
+	}
+
+	return entries, nil
+}
+
+func (sc *scrumClient) Stat(ctx context.Context, scrumDate civilDate, user string) (*ScrumEntry, error) {
+	objectPath := mantaScrumPath(scrumDate, user)
+
+	var ent ScrumEntry
+	err := sc.do(ctx, "GetInfo", objectPath, &sc.headCalls, func(ctx context.Context) error {
+		respBody, respHeaders, err := sc.execute(ctx, http.MethodHead, objectPath)
+		if err != nil {
+			return errors.Wrap(err, "unable to get info")
+		}
+		respBody.Close()
+
+		ent = mantaScrumEntry(user, respHeaders)
+
+		return nil
+	})
+	if err != nil {
+		return nil, mantaError(err, "unable to stat manta object")
+	}
+
+	return &ent, nil
+}
+
+func (sc *scrumClient) GetFile(ctx context.Context, name string) ([]byte, error) {
+	objectPath := path.Join("stor", "scrum", name)
+
+	var body []byte
+	err := sc.do(ctx, "GetObject", objectPath, &sc.getCalls, func(ctx context.Context) error {
+		respBody, _, err := sc.execute(ctx, http.MethodGet, objectPath)
+		if err != nil {
+			return errors.Wrap(err, "unable to get object")
+		}
+		defer respBody.Close()
+
+		body, err = ioutil.ReadAll(respBody)
+		return errors.Wrap(err, "unable to read manta object")
+	})
+	if err != nil {
+		return nil, mantaError(err, "unable to get manta object")
+	}
+
+	return body, nil
+}
+
+// mantaError translates Manta's "not found" and "precondition failed" errors
+// into ErrScrumNotFound and ErrScrumConflict and wraps every other error with
+// msg.
+func mantaError(err error, msg string) error {
+	switch {
+	case tritonError.IsResourceNotFoundError(err) || tritonError.IsStatusNotFoundCode(err):
+		return errors.Wrap(ErrScrumNotFound, err.Error())
+	case tritonError.IsPreconditionFailedError(err) || tritonError.IsSpecificStatusCode(err, http.StatusPreconditionFailed):
+		return errors.Wrap(ErrScrumConflict, err.Error())
+	}
+
+	return errors.Wrap(err, msg)
+}
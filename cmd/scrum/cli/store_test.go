@@ -0,0 +1,463 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testStores returns every storage backend, each backed by env.
+func testStores(t *testing.T, env *testEnv) map[string]ScrumStore {
+	mantaStore, err := getScrumStore()
+	if err != nil {
+		t.Fatalf("unable to create manta store: %v", err)
+	}
+
+	localDir := filepath.Join(env.dir, "scrum")
+	if err := os.Mkdir(localDir, 0755); err != nil {
+		t.Fatalf("unable to create local store directory: %v", err)
+	}
+
+	localStore, err := newLocalStore(localDir)
+	if err != nil {
+		t.Fatalf("unable to create local store: %v", err)
+	}
+
+	return map[string]ScrumStore{
+		storageBackendManta: mantaStore,
+		storageBackendLocal: localStore,
+	}
+}
+
+func TestStorePutPreconditions(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	ctx := context.Background()
+	date := newCivilDate(2018, time.March, 12)
+
+	for name, store := range testStores(t, env) {
+		put := func(body string, opts PutOptions) error {
+			return store.Put(ctx, date, "alice", strings.NewReader(body), opts)
+		}
+
+		if err := put("first\n", PutOptions{IfNotExist: true}); err != nil {
+			t.Fatalf("%s: unable to create scrum: %v", name, err)
+		}
+
+		if err := put("second\n", PutOptions{IfNotExist: true}); !isScrumConflictError(err) {
+			t.Errorf("%s: create of an existing scrum: error = %v, want a conflict", name, err)
+		}
+
+		seen, err := store.Stat(ctx, date, "alice")
+		if err != nil {
+			t.Fatalf("%s: unable to stat scrum: %v", name, err)
+		}
+
+		if err := put("replaced by someone else\n", PutOptions{}); err != nil {
+			t.Fatalf("%s: unable to replace scrum: %v", name, err)
+		}
+
+		if err := put("replaced\n", PutOptions{IfMatch: seen.ETag}); !isScrumConflictError(err) {
+			t.Errorf("%s: replace of a changed scrum: error = %v, want a conflict", name, err)
+		}
+
+		current, err := store.Get(ctx, date, "alice")
+		if err != nil {
+			t.Fatalf("%s: unable to get scrum: %v", name, err)
+		}
+
+		if got, want := string(current.Body), "replaced by someone else\n"; got != want {
+			t.Errorf("%s: body = %q, want %q", name, got, want)
+		}
+
+		if err := put("replaced\n", PutOptions{IfMatch: current.ETag}); err != nil {
+			t.Errorf("%s: unable to replace the current scrum: %v", name, err)
+		}
+	}
+}
+
+func TestLocalStoreETag(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	ctx := context.Background()
+	date := newCivilDate(2018, time.March, 12)
+	store := testStores(t, env)[storageBackendLocal]
+
+	put := func(body string, opts PutOptions) error {
+		return store.Put(ctx, date, "alice", strings.NewReader(body), opts)
+	}
+
+	if err := put("first\n", PutOptions{}); err != nil {
+		t.Fatalf("unable to put scrum: %v", err)
+	}
+
+	seen, err := store.Stat(ctx, date, "alice")
+	if err != nil {
+		t.Fatalf("unable to stat scrum: %v", err)
+	}
+
+	// A filesystem with coarse mtimes gives a write of the same size in the
+	// same tick the same mtime.
+	if err := put("other\n", PutOptions{}); err != nil {
+		t.Fatalf("unable to replace scrum: %v", err)
+	}
+
+	filename := filepath.Join(env.dir, "scrum", "2018", "03", "12", "alice")
+	if err := os.Chtimes(filename, seen.ModifiedTime, seen.ModifiedTime); err != nil {
+		t.Fatalf("unable to reset mtime: %v", err)
+	}
+
+	if err := put("replaced\n", PutOptions{IfMatch: seen.ETag}); !isScrumConflictError(err) {
+		t.Errorf("replace of a changed scrum: error = %v, want a conflict", err)
+	}
+
+	// Changing only the metadata changes the ETag too.
+	current, err := store.Stat(ctx, date, "alice")
+	if err != nil {
+		t.Fatalf("unable to stat scrum: %v", err)
+	}
+
+	if err := put("other\n", PutOptions{Metadata: ScrumMetadata{Status: scrumStatusSick}}); err != nil {
+		t.Fatalf("unable to replace scrum: %v", err)
+	}
+
+	if err := put("replaced\n", PutOptions{IfMatch: current.ETag}); !isScrumConflictError(err) {
+		t.Errorf("replace of a scrum with changed metadata: error = %v, want a conflict", err)
+	}
+}
+
+func TestStoreDeletePreconditions(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	ctx := context.Background()
+	date := newCivilDate(2018, time.March, 12)
+
+	for name, store := range testStores(t, env) {
+		if err := store.Put(ctx, date, "alice", strings.NewReader("vacation\n"), PutOptions{}); err != nil {
+			t.Fatalf("%s: unable to put scrum: %v", name, err)
+		}
+
+		seen, err := store.Stat(ctx, date, "alice")
+		if err != nil {
+			t.Fatalf("%s: unable to stat scrum: %v", name, err)
+		}
+
+		if err := store.Put(ctx, date, "alice", strings.NewReader("back early\n"), PutOptions{}); err != nil {
+			t.Fatalf("%s: unable to replace scrum: %v", name, err)
+		}
+
+		if err := store.Delete(ctx, date, "alice", DeleteOptions{IfMatch: seen.ETag}); !isScrumConflictError(err) {
+			t.Errorf("%s: delete of a changed scrum: error = %v, want a conflict", name, err)
+		}
+
+		current, err := store.Stat(ctx, date, "alice")
+		if err != nil {
+			t.Fatalf("%s: scrum was deleted: %v", name, err)
+		}
+
+		if err := store.Delete(ctx, date, "alice", DeleteOptions{IfMatch: current.ETag}); err != nil {
+			t.Errorf("%s: unable to delete the current scrum: %v", name, err)
+		}
+
+		if _, err := store.Stat(ctx, date, "alice"); !isScrumNotFoundError(err) {
+			t.Errorf("%s: stat of a deleted scrum: error = %v, want not found", name, err)
+		}
+	}
+}
+
+func TestMantaStoreCreatesDirectoriesLazily(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	ctx := context.Background()
+	date := newCivilDate(2018, time.March, 12)
+	store := testStores(t, env)[storageBackendManta]
+
+	// The first scrum of the day creates the year, month and day directories
+	// after Manta reports that they are missing.
+	if err := store.Put(ctx, date, "alice", strings.NewReader("first\n"), PutOptions{}); err != nil {
+		t.Fatalf("unable to put scrum: %v", err)
+	}
+
+	if got, want := env.manta.Requests(http.MethodPut), uint64(5); got != want {
+		t.Errorf("PUT requests for the first scrum = %d, want %d", got, want)
+	}
+
+	// Every later scrum of the day is a single PUT.
+	if err := store.Put(ctx, date, "bob", strings.NewReader("second\n"), PutOptions{}); err != nil {
+		t.Fatalf("unable to put scrum: %v", err)
+	}
+
+	if got, want := env.manta.Requests(http.MethodPut), uint64(6); got != want {
+		t.Errorf("PUT requests after the second scrum = %d, want %d", got, want)
+	}
+
+	if got := env.manta.Requests(http.MethodHead); got != 0 {
+		t.Errorf("HEAD requests = %d, want 0", got)
+	}
+}
+
+func TestStoreLinkPreconditions(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	ctx := context.Background()
+	monday := newCivilDate(2018, time.March, 12)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	for name, store := range testStores(t, env) {
+		if err := store.Put(ctx, monday, "alice", strings.NewReader("recycled\n"), PutOptions{}); err != nil {
+			t.Fatalf("%s: unable to put scrum: %v", name, err)
+		}
+
+		if err := store.Link(ctx, monday, tuesday, "alice", LinkOptions{IfNotExist: true}); err != nil {
+			t.Fatalf("%s: unable to link scrum: %v", name, err)
+		}
+
+		if err := store.Link(ctx, monday, tuesday, "alice", LinkOptions{IfNotExist: true}); !isScrumConflictError(err) {
+			t.Errorf("%s: link over an existing scrum: error = %v, want a conflict", name, err)
+		}
+
+		seen, err := store.Stat(ctx, tuesday, "alice")
+		if err != nil {
+			t.Fatalf("%s: unable to stat scrum: %v", name, err)
+		}
+
+		if err := store.Put(ctx, tuesday, "alice", strings.NewReader("teammate's scrum\n"), PutOptions{}); err != nil {
+			t.Fatalf("%s: unable to replace scrum: %v", name, err)
+		}
+
+		if err := store.Link(ctx, monday, tuesday, "alice", LinkOptions{IfMatch: seen.ETag}); !isScrumConflictError(err) {
+			t.Errorf("%s: link over a changed scrum: error = %v, want a conflict", name, err)
+		}
+
+		obj, err := store.Get(ctx, tuesday, "alice")
+		if err != nil {
+			t.Fatalf("%s: unable to get scrum: %v", name, err)
+		}
+
+		if got, want := string(obj.Body), "teammate's scrum\n"; got != want {
+			t.Errorf("%s: body = %q, want %q", name, got, want)
+		}
+
+		if err := store.Link(ctx, monday, tuesday, "alice", LinkOptions{IfMatch: obj.ETag}); err != nil {
+			t.Errorf("%s: unable to link over the seen scrum: %v", name, err)
+		}
+	}
+}
+
+func TestMantaStoreListDayPages(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	date := newCivilDate(2018, time.March, 12)
+	n := mantaListLimit + 10
+	for i := 0; i < n; i++ {
+		env.manta.PutObject(fmt.Sprintf("stor/scrum/2018/03/12/user%04d", i), []byte("scrum\n"))
+	}
+
+	store := testStores(t, env)[storageBackendManta]
+	entries, err := store.ListDay(context.Background(), date)
+	if err != nil {
+		t.Fatalf("unable to list day: %v", err)
+	}
+
+	if len(entries) != n {
+		t.Fatalf("entries = %d, want %d", len(entries), n)
+	}
+
+	for i, ent := range entries {
+		if want := fmt.Sprintf("user%04d", i); ent.Name != want {
+			t.Fatalf("entry %d = %q, want %q", i, ent.Name, want)
+		}
+	}
+
+	if got, want := env.manta.Requests(http.MethodGet), uint64(2); got != want {
+		t.Errorf("GET requests = %d, want %d", got, want)
+	}
+}
+
+func TestStoreLink(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	ctx := context.Background()
+	monday := newCivilDate(2018, time.March, 12)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	for name, store := range testStores(t, env) {
+		if err := store.Put(ctx, monday, "alice", strings.NewReader("recycled\n"), PutOptions{}); err != nil {
+			t.Fatalf("%s: unable to put scrum: %v", name, err)
+		}
+
+		if err := store.Link(ctx, monday, tuesday, "alice", LinkOptions{}); err != nil {
+			t.Fatalf("%s: unable to link scrum: %v", name, err)
+		}
+
+		obj, err := store.Get(ctx, tuesday, "alice")
+		if err != nil {
+			t.Fatalf("%s: unable to get linked scrum: %v", name, err)
+		}
+
+		if got, want := string(obj.Body), "recycled\n"; got != want {
+			t.Errorf("%s: body = %q, want %q", name, got, want)
+		}
+
+		if err := store.Link(ctx, monday, tuesday, "bob", LinkOptions{}); !isScrumNotFoundError(err) {
+			t.Errorf("%s: link of a missing scrum: error = %v, want not found", name, err)
+		}
+	}
+}
+
+func TestLocalStoreLinkWithoutHardLinks(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	localLink = func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EXDEV}
+	}
+	defer func() { localLink = os.Link }()
+
+	ctx := context.Background()
+	monday := newCivilDate(2018, time.March, 12)
+	tuesday := monday.AddDate(0, 0, 1)
+	store := testStores(t, env)[storageBackendLocal]
+
+	md := ScrumMetadata{Status: scrumStatusVacation, EndDate: tuesday}
+	if err := store.Put(ctx, monday, "alice", strings.NewReader("vacation\n"), PutOptions{Metadata: md}); err != nil {
+		t.Fatalf("unable to put scrum: %v", err)
+	}
+
+	if err := store.Link(ctx, monday, tuesday, "alice", LinkOptions{}); err != nil {
+		t.Fatalf("unable to copy scrum: %v", err)
+	}
+
+	obj, err := store.Get(ctx, tuesday, "alice")
+	if err != nil {
+		t.Fatalf("unable to get copied scrum: %v", err)
+	}
+
+	if got, want := string(obj.Body), "vacation\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	if obj.Metadata != md {
+		t.Errorf("metadata = %+v, want %+v", obj.Metadata, md)
+	}
+
+	if err := store.Link(ctx, monday, tuesday, "bob", LinkOptions{}); !isScrumNotFoundError(err) {
+		t.Errorf("copy of a missing scrum: error = %v, want not found", err)
+	}
+}
+
+func TestLocalStoreMetadataIsAtomic(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	ctx := context.Background()
+	date := newCivilDate(2018, time.March, 12)
+	store := testStores(t, env)[storageBackendLocal]
+
+	old := ScrumMetadata{Status: scrumStatusSick, EndDate: date}
+	if err := store.Put(ctx, date, "alice", strings.NewReader("sick\n"), PutOptions{Metadata: old}); err != nil {
+		t.Fatalf("unable to put scrum: %v", err)
+	}
+
+	seen, err := store.Stat(ctx, date, "alice")
+	if err != nil {
+		t.Fatalf("unable to stat scrum: %v", err)
+	}
+
+	// A reader between Put's update of the metadata and its rename of the scrum
+	// sees the old scrum with its old metadata.
+	filename := filepath.Join(env.dir, "scrum", "2018", "03", "12", "alice")
+	next := ScrumMetadata{Status: scrumStatusNormal}
+	if err := writeLocalMetadata(filename, localBodyHash([]byte("better\n")), next.fields()); err != nil {
+		t.Fatalf("unable to write metadata: %v", err)
+	}
+
+	ent, err := store.Stat(ctx, date, "alice")
+	if err != nil {
+		t.Fatalf("unable to stat scrum: %v", err)
+	}
+
+	if ent.Metadata != old || ent.ETag != seen.ETag {
+		t.Errorf("metadata = %+v (etag %s), want %+v (etag %s)", ent.Metadata, ent.ETag, old, seen.ETag)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("better\n"), 0644); err != nil {
+		t.Fatalf("unable to replace scrum: %v", err)
+	}
+
+	obj, err := store.Get(ctx, date, "alice")
+	if err != nil {
+		t.Fatalf("unable to get scrum: %v", err)
+	}
+
+	if obj.Metadata != next {
+		t.Errorf("metadata = %+v, want %+v", obj.Metadata, next)
+	}
+}
+
+func TestStoreMetadata(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	ctx := context.Background()
+	monday := newCivilDate(2018, time.March, 12)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	md := ScrumMetadata{
+		Status:        scrumStatusSick,
+		EndDate:       newCivilDate(2018, time.March, 14),
+		Author:        "bob",
+		ClientVersion: "1.2.3",
+		SourceFile:    "today.md",
+	}
+
+	for name, store := range testStores(t, env) {
+		if err := store.Put(ctx, monday, "alice", strings.NewReader("sick\n"), PutOptions{Metadata: md}); err != nil {
+			t.Fatalf("%s: unable to put scrum: %v", name, err)
+		}
+
+		if err := store.Link(ctx, monday, tuesday, "alice", LinkOptions{}); err != nil {
+			t.Fatalf("%s: unable to link scrum: %v", name, err)
+		}
+
+		ent, err := store.Stat(ctx, tuesday, "alice")
+		if err != nil {
+			t.Fatalf("%s: unable to stat scrum: %v", name, err)
+		}
+
+		if ent.Metadata != md {
+			t.Errorf("%s: metadata = %+v, want %+v", name, ent.Metadata, md)
+		}
+
+		obj, err := store.Get(ctx, monday, "alice")
+		if err != nil {
+			t.Fatalf("%s: unable to get scrum: %v", name, err)
+		}
+
+		if obj.Metadata != md {
+			t.Errorf("%s: metadata = %+v, want %+v", name, obj.Metadata, md)
+		}
+
+		if err := store.Put(ctx, monday, "alice", strings.NewReader("better\n"), PutOptions{}); err != nil {
+			t.Fatalf("%s: unable to replace scrum: %v", name, err)
+		}
+
+		if ent, err := store.Stat(ctx, monday, "alice"); err != nil || ent.Metadata != (ScrumMetadata{}) {
+			t.Errorf("%s: replaced scrum: metadata = %+v, err = %v, want no metadata", name, ent, err)
+		}
+	}
+}
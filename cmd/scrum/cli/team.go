@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// teamFileName is the name of the team roster shared through the scrum
+// store.  A local roster set with team.file takes precedence.
+const teamFileName = "team.json"
+
+// teamMember is a single member of the team roster.
+type teamMember struct {
+	User    string `json:"-"`
+	Name    string `json:"name,omitempty"`
+	Country string `json:"country,omitempty"`
+	Manager string `json:"manager,omitempty"`
+}
+
+// teamRoster is the team roster keyed by username.  The roster file is a JSON
+// object, e.g.:
+//
+//	{
+//	  "alice": {"name": "Alice Smith", "country": "us", "manager": "carol"},
+//	  "bob": {"country": "uk", "manager": "carol"}
+//	}
+//
+// A member without a country observes the holidays of general.country.
+type teamRoster map[string]*teamMember
+
+// parseTeamRoster parses a team roster file.
+func parseTeamRoster(buf []byte) (teamRoster, error) {
+	var roster teamRoster
+	if err := json.Unmarshal(buf, &roster); err != nil {
+		return nil, errors.Wrap(err, "unable to parse team roster")
+	}
+
+	for user, member := range roster {
+		if member == nil {
+			member = &teamMember{}
+			roster[user] = member
+		}
+
+		member.User = user
+		member.Country = strings.ToLower(member.Country)
+		if member.Country == "" {
+			member.Country = viper.GetString(configKeyCountry)
+		}
+	}
+
+	return roster, nil
+}
+
+// loadTeamRoster reads the team roster from team.file, or from the scrum
+// store when team.file is not set.  ErrScrumNotFound is returned when there is
+// no roster.
+func loadTeamRoster(ctx context.Context, store ScrumStore) (teamRoster, error) {
+	var buf []byte
+	if rawFilename := viper.GetString(configKeyTeamFile); rawFilename != "" {
+		filename, err := homedir.Expand(rawFilename)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to find a user's home directory")
+		}
+
+		buf, err = ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read team roster")
+		}
+	} else {
+		var err error
+		buf, err = store.GetFile(ctx, teamFileName)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get team roster")
+		}
+	}
+
+	return parseTeamRoster(buf)
+}
+
+// members returns the members of the roster sorted by username.
+func (r teamRoster) members() []*teamMember {
+	members := make([]*teamMember, 0, len(r))
+	for _, member := range r {
+		members = append(members, member)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].User < members[j].User
+	})
+
+	return members
+}
+
+// works returns true if date is part of any member's work week.
+func (r teamRoster) works(date civilDate) bool {
+	for user := range r {
+		if getWorkWeek(user).has(date.Weekday()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkTeamMember warns when user is not on the local team roster, team.file,
+// which usually means the username is misspelled.  The roster in the scrum
+// store is not checked so that writing a scrum never waits for it.  A missing
+// roster is not an error.
+func checkTeamMember(store ScrumStore, user string) {
+	if viper.GetString(configKeyTeamFile) == "" {
+		return
+	}
+
+	roster, err := loadTeamRoster(cmdCtx, store)
+	switch {
+	case err != nil && isScrumNotFoundError(err):
+		return
+	case err != nil:
+		log.Debug().Err(err).Msg("unable to load team roster, not checking username")
+		return
+	}
+
+	if _, found := roster[user]; !found {
+		log.Warn().Str("username", user).Msg("user is not on the team roster")
+	}
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// defaultWorkWeek is the work week of everyone without a configured one.
+const defaultWorkWeek = "mon-fri"
+
+// workWeek is the set of days someone scrums on, one bit per time.Weekday.
+type workWeek uint8
+
+// workWeekDays maps the names accepted by parseWorkWeek to their weekday.
+var workWeekDays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// parseWorkWeek parses a comma separated list of days and ranges of days, e.g.
+// "mon-fri", "sun-thu" or "mon-wed,fri".  A range may wrap around the end of
+// the week, e.g. "fri-mon".
+func parseWorkWeek(spec string) (workWeek, error) {
+	var week workWeek
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(item, "-", 2)
+		first, found := workWeekDays[strings.TrimSpace(bounds[0])]
+		if !found {
+			return 0, errors.Errorf("invalid day %q in work week %q", bounds[0], spec)
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			if last, found = workWeekDays[strings.TrimSpace(bounds[1])]; !found {
+				return 0, errors.Errorf("invalid day %q in work week %q", bounds[1], spec)
+			}
+		}
+
+		for day := first; ; day = (day + 1) % 7 {
+			week |= 1 << uint(day)
+			if day == last {
+				break
+			}
+		}
+	}
+
+	if week == 0 {
+		return 0, errors.Errorf("work week %q has no days", spec)
+	}
+
+	return week, nil
+}
+
+// has returns true if day is part of the work week.
+func (w workWeek) has(day time.Weekday) bool {
+	return w&(1<<uint(day)) != 0
+}
+
+// start returns the day the work week starts on: the first work day, counting
+// from Monday, that follows a day off.  A seven day work week starts on Monday.
+func (w workWeek) start() time.Weekday {
+	for i := 0; i < 7; i++ {
+		day := (time.Monday + time.Weekday(i)) % 7
+		if w.has(day) && !w.has((day+6)%7) {
+			return day
+		}
+	}
+
+	return time.Monday
+}
+
+// weekOf returns the first day of date's week, counting from the start of the
+// work week.
+func (w workWeek) weekOf(date civilDate) civilDate {
+	return date.AddDate(0, 0, -((int(date.Weekday()) - int(w.start()) + 7) % 7))
+}
+
+// getWorkWeek returns user's work week: the user's entry in work-weeks, or
+// general.work-week.  An invalid work week is logged and replaced with
+// defaultWorkWeek.
+func getWorkWeek(user string) workWeek {
+	spec := viper.GetStringMapString(configKeyWorkWeeks)[strings.ToLower(user)]
+	if spec == "" {
+		spec = viper.GetString(configKeyWorkWeek)
+	}
+
+	week, err := parseWorkWeek(spec)
+	if err != nil {
+		log.Warn().Err(err).Str("username", user).Msgf("invalid work week, using %q", defaultWorkWeek)
+		week, _ = parseWorkWeek(defaultWorkWeek)
+	}
+
+	return week
+}
+
+// getMyWorkWeek returns the scrum user's work week.
+func getMyWorkWeek() workWeek {
+	return getWorkWeek(interpolateUserEnvVar(viper.GetString(configKeyScrumUsername)))
+}
+
+// getTeamWorkWeek returns the days that are part of anyone's work week: the
+// general work week and every user's entry in work-weeks.
+func getTeamWorkWeek() workWeek {
+	week := getWorkWeek("")
+	for user := range viper.GetStringMapString(configKeyWorkWeeks) {
+		week |= getWorkWeek(user)
+	}
+
+	return week
+}
+
+// isWorkDay returns true if date is part of the scrum user's work week.
+func isWorkDay(date civilDate) bool {
+	return getMyWorkWeek().has(date.Weekday())
+}
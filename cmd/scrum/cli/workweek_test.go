@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseWorkWeek(t *testing.T) {
+	for _, test := range []struct {
+		spec string
+		days []time.Weekday
+		err  string
+	}{
+		{spec: "mon-fri", days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}},
+		{spec: "Sun-Thu", days: []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday}},
+		{spec: "monday-wednesday, friday", days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Friday}},
+		{spec: "fri-mon", days: []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}},
+		{spec: "tue", days: []time.Weekday{time.Tuesday}},
+		{spec: "mon-fry", err: `invalid day "fry"`},
+		{spec: "funday", err: `invalid day "funday"`},
+		{spec: " , ", err: "has no days"},
+	} {
+		week, err := parseWorkWeek(test.spec)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: error = %v, want %q", test.spec, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.spec, err)
+			continue
+		}
+
+		want := make(map[time.Weekday]bool)
+		for _, day := range test.days {
+			want[day] = true
+		}
+
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if got := week.has(day); got != want[day] {
+				t.Errorf("%q: has(%s) = %t, want %t", test.spec, day, got, want[day])
+			}
+		}
+	}
+}
+
+func TestWorkWeekNavigation(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyWorkWeeks, map[string]string{
+		"alice": "sun-thu",
+		"carol": "mon-thu",
+	})
+	defer viper.Set(configKeyWorkWeeks, nil)
+
+	env.manta.PutObject("stor/scrum/2018/03/09/alice", []byte("friday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/11/alice", []byte("sunday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/09/bob", []byte("friday\n"))
+	env.manta.PutObject("stor/scrum/2018/03/08/carol", []byte("thursday\n"))
+
+	for user, want := range map[string]string{
+		"alice": "sunday\n",
+		"bob":   "friday\n",
+		"carol": "thursday\n",
+	} {
+		if out := env.mustRun("get", "-u", user, "-y"); out != want {
+			t.Errorf("%s's yesterday = %q, want %q", user, out, want)
+		}
+	}
+
+	input := env.writeFile("today.md", "did things\n")
+	env.mustRun("set", "-u", "alice", "-D", "2018-03-15", "-d", "3", "-i", input)
+	for date, want := range map[string]bool{
+		"2018/03/15": true,
+		"2018/03/16": false,
+		"2018/03/17": false,
+		"2018/03/18": true,
+		"2018/03/19": true,
+	} {
+		if _, found := env.manta.Object("stor/scrum/" + date + "/alice"); found != want {
+			t.Errorf("alice's scrum on %s exists = %t, want %t", date, found, want)
+		}
+	}
+
+	env.mustRun("set", "-u", "carol", "-D", "2018-03-15", "-t", "-i", input)
+	if _, found := env.manta.Object("stor/scrum/2018/03/19/carol"); !found {
+		t.Errorf("carol's scrum for tomorrow was not written on Monday")
+	}
+}
+
+func TestMissingWorkWeek(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.manta.PutObject("stor/scrum/team.json", []byte(testTeamRoster))
+
+	viper.Set(configKeyWorkWeeks, map[string]string{"alice": "sun-thu"})
+	defer viper.Set(configKeyWorkWeeks, nil)
+
+	out := env.mustRun("missing", "-D", "2018-03-16")
+	if line := missingLine(out, "alice"); line != "" {
+		t.Errorf("alice does not work on Fridays but is listed:\n%s", out)
+	}
+
+	if line := missingLine(out, "bob"); !strings.Contains(line, "missing") {
+		t.Errorf("bob is not listed as missing:\n%s", out)
+	}
+
+	out = env.mustRun("missing", "-D", "2018-03-18")
+	if line := missingLine(out, "alice"); !strings.Contains(line, "missing") {
+		t.Errorf("alice works on Sundays but is not listed as missing:\n%s", out)
+	}
+
+	if line := missingLine(out, "bob"); line != "" {
+		t.Errorf("bob does not work on Sundays but is listed:\n%s", out)
+	}
+}
+
+func TestWorkWeekStart(t *testing.T) {
+	for spec, want := range map[string]time.Weekday{
+		"mon-fri":     time.Monday,
+		"sun-thu":     time.Sunday,
+		"fri-mon":     time.Friday,
+		"mon-wed,fri": time.Monday,
+		"mon-sun":     time.Monday,
+	} {
+		week, err := parseWorkWeek(spec)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", spec, err)
+		}
+
+		if got := week.start(); got != want {
+			t.Errorf("%q: start() = %s, want %s", spec, got, want)
+		}
+	}
+}
+
+func TestWorkWeekRanges(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyWorkWeek, "sun-thu")
+	defer viper.Set(configKeyWorkWeek, defaultWorkWeek)
+
+	today, _ := getDateInLocation("2018-03-12")
+	since, until, err := parseDateRangeExpr("this week", today)
+	if err != nil {
+		t.Fatalf("unable to parse this week: %v", err)
+	}
+
+	if got, want := since.Format(dateInputFormat)+".."+until.Format(dateInputFormat), "2018-03-11..2018-03-17"; got != want {
+		t.Errorf("this week = %s, want %s", got, want)
+	}
+
+	out := env.mustRun("ooo")
+	if want := "Out this week (2018-03-11 to 2018-03-15)"; !strings.Contains(out, want) {
+		t.Errorf("output does not contain %q:\n%s", want, out)
+	}
+}
+
+func TestCalendarExportWorkWeek(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	viper.Set(configKeyWorkWeeks, map[string]string{"alice": "sun-thu"})
+	defer viper.Set(configKeyWorkWeeks, nil)
+
+	env.mustRun("set", "-u", "alice", "-D", "2018-03-11", "--until", "2018-03-11")
+
+	out := env.mustRun("calendar", "export", "--since", "2018-03-11", "--until", "2018-03-11")
+	if !strings.Contains(out, "SUMMARY:alice: vacation") {
+		t.Errorf("alice's Sunday vacation was not exported:\n%s", out)
+	}
+}
@@ -0,0 +1,342 @@
+package mantatest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// job is a compute job.  Jobs run to completion when their input is ended.
+type job struct {
+	id        string
+	name      string
+	phases    []jobPhase
+	inputs    []string
+	inputDone bool
+	state     string
+	created   time.Time
+	done      time.Time
+	tasks     int
+	outputs   []string
+	failures  []jobFailure
+}
+
+// jobPhase is the subset of a Manta job phase run by the fake.
+type jobPhase struct {
+	Type string `json:"type"`
+	Exec string `json:"exec"`
+	Init string `json:"init,omitempty"`
+}
+
+// jobFailure is a single entry of a job's error stream.
+type jobFailure struct {
+	Phase string        `json:"phase"`
+	What  string        `json:"what"`
+	Input string        `json:"input,omitempty"`
+	Error jobFailureErr `json:"error"`
+}
+
+type jobFailureErr struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Stderr  string `json:"stderr,omitempty"`
+}
+
+// taskOutput is the output of a single task, keyed by the input it was run
+// against.
+type taskOutput struct {
+	input string
+	body  []byte
+}
+
+// serveJobs serves the job endpoints below /account/jobs.  Requests for the
+// objects created by a job (/account/jobs/ID/stor/...) are served as objects.
+func (s *Server) serveJobs(w http.ResponseWriter, r *http.Request, absPath string) {
+	root := path.Join("/", s.account, "jobs")
+	parts := strings.Split(strings.TrimPrefix(absPath, root), "/")[1:]
+
+	switch {
+	case absPath == root && r.Method == http.MethodPost:
+		s.createJob(w, r)
+		return
+	case len(parts) < 3 || parts[1] != "live":
+		writeError(w, r, http.StatusNotFound, "ResourceNotFound", absPath+" was not found")
+		return
+	}
+
+	s.lock.Lock()
+	j, found := s.jobs[parts[0]]
+	s.lock.Unlock()
+	if !found {
+		writeError(w, r, http.StatusNotFound, "ResourceNotFound", "job "+parts[0]+" was not found")
+		return
+	}
+
+	switch endpoint := strings.Join(parts[2:], "/"); {
+	case endpoint == "in" && r.Method == http.MethodPost:
+		s.addJobInputs(w, r, j)
+	case endpoint == "in/end" && r.Method == http.MethodPost:
+		s.endJobInput(w, r, j)
+	case endpoint == "status" && r.Method == http.MethodGet:
+		s.getJobStatus(w, r, j)
+	case endpoint == "out" && r.Method == http.MethodGet:
+		s.lock.Lock()
+		outputs := append([]string(nil), j.outputs...)
+		s.lock.Unlock()
+
+		writeJobStream(w, "text/plain", len(outputs), func(w *bufio.Writer) {
+			for _, output := range outputs {
+				fmt.Fprintln(w, output)
+			}
+		})
+	case endpoint == "fail" && r.Method == http.MethodGet:
+		s.lock.Lock()
+		failures := append([]jobFailure(nil), j.failures...)
+		s.lock.Unlock()
+
+		writeJobStream(w, "application/x-json-stream", len(failures), func(w *bufio.Writer) {
+			enc := json.NewEncoder(w)
+			for _, f := range failures {
+				enc.Encode(f)
+			}
+		})
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "BadRequest", r.Method+" "+absPath+" is not supported")
+	}
+}
+
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	var spec struct {
+		Name   string     `json:"name"`
+		Phases []jobPhase `json:"phases"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		writeError(w, r, http.StatusBadRequest, "InvalidJobError", err.Error())
+		return
+	}
+
+	if len(spec.Phases) == 0 {
+		writeError(w, r, http.StatusBadRequest, "InvalidJobError", "job has no phases")
+		return
+	}
+
+	for _, phase := range spec.Phases {
+		if phase.Type != "map" && phase.Type != "reduce" {
+			writeError(w, r, http.StatusBadRequest, "InvalidJobError", "unsupported phase type "+strconv.Quote(phase.Type))
+			return
+		}
+	}
+
+	s.lock.Lock()
+	s.jobID++
+	j := &job{
+		id:      fmt.Sprintf("%08x-0000-4000-8000-000000000000", s.jobID),
+		name:    spec.Name,
+		phases:  spec.Phases,
+		state:   "running",
+		created: s.now(),
+	}
+	s.jobs[j.id] = j
+	s.lock.Unlock()
+
+	w.Header().Set("Location", path.Join("/", s.account, "jobs", j.id))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) addJobInputs(w http.ResponseWriter, r *http.Request, j *job) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if j.inputDone {
+		writeError(w, r, http.StatusConflict, "InvalidJobStateError", "job "+j.id+" input is done")
+		return
+	}
+
+	for _, input := range strings.Split(string(body), "\n") {
+		if input = strings.TrimSpace(input); input != "" {
+			j.inputs = append(j.inputs, path.Clean(input))
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// endJobInput ends the job's input and runs the job.
+func (s *Server) endJobInput(w http.ResponseWriter, r *http.Request, j *job) {
+	s.lock.Lock()
+	alreadyDone := j.inputDone
+	j.inputDone = true
+	s.lock.Unlock()
+
+	if !alreadyDone {
+		s.runJob(j)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) getJobStatus(w http.ResponseWriter, r *http.Request, j *job) {
+	s.lock.Lock()
+	status := map[string]interface{}{
+		"id":          j.id,
+		"name":        j.name,
+		"phases":      j.phases,
+		"state":       j.state,
+		"cancelled":   false,
+		"inputDone":   j.inputDone,
+		"timeCreated": j.created.UTC().Format(time.RFC3339Nano),
+		"stats": map[string]int{
+			"errors":    len(j.failures),
+			"outputs":   len(j.outputs),
+			"retries":   0,
+			"tasks":     j.tasks,
+			"tasksDone": j.tasks,
+		},
+	}
+	if j.state == "done" {
+		status["timeDone"] = j.done.UTC().Format(time.RFC3339Nano)
+	}
+	s.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// runJob runs every phase of j on the local host.  Like Manta, each task's
+// exec is run by sh(1) with the input object on stdin and its path in
+// MANTA_INPUT_OBJECT, map tasks run once per input and reduce tasks run once
+// with the concatenated output of the previous phase.  The outputs of the
+// last phase are stored as objects below /account/jobs/ID/stor.
+func (s *Server) runJob(j *job) {
+	s.lock.Lock()
+	var tasks []taskOutput
+	for _, input := range j.inputs {
+		n, found := s.nodes[input]
+		if !found || n.dir {
+			j.failures = append(j.failures, jobFailure{
+				Phase: "0",
+				What:  "phase 0: input " + strconv.Quote(input),
+				Input: input,
+				Error: jobFailureErr{Code: "ResourceNotFoundError", Message: "no such object: " + input},
+			})
+			continue
+		}
+
+		tasks = append(tasks, taskOutput{input: input, body: n.body})
+	}
+	phases := j.phases
+	s.lock.Unlock()
+
+	for i, phase := range phases {
+		if phase.Type == "reduce" {
+			var body bytes.Buffer
+			for _, task := range tasks {
+				body.Write(task.body)
+			}
+			tasks = []taskOutput{{input: "reduce", body: body.Bytes()}}
+		}
+
+		var outputs []taskOutput
+		for _, task := range tasks {
+			s.lock.Lock()
+			j.tasks++
+			s.lock.Unlock()
+
+			out, err := runJobTask(phase, task)
+			if err != nil {
+				s.lock.Lock()
+				j.failures = append(j.failures, jobFailure{
+					Phase: strconv.Itoa(i),
+					What:  fmt.Sprintf("phase %d: %s input %q", i, phase.Type, task.input),
+					Input: task.input,
+					Error: jobFailureErr{Code: "UserTaskError", Message: err.Error(), Stderr: string(out)},
+				})
+				s.lock.Unlock()
+				continue
+			}
+
+			outputs = append(outputs, taskOutput{input: task.input, body: out})
+		}
+		tasks = outputs
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stor := path.Join("/", s.account, "jobs", j.id, "stor")
+	for i, task := range tasks {
+		outputPath := path.Join(stor, fmt.Sprintf("%s.%d.%d", strings.TrimPrefix(task.input, "/"), len(phases)-1, i))
+		s.mkdirAll(path.Dir(outputPath))
+		s.nodes[outputPath] = s.newObject(task.body, "application/octet-stream", http.Header{})
+		j.outputs = append(j.outputs, outputPath)
+	}
+
+	j.state = "done"
+	j.done = s.now()
+}
+
+// runJobTask runs a single task of phase.  On failure the task's stderr is
+// returned with the error.
+func runJobTask(phase jobPhase, task taskOutput) ([]byte, error) {
+	input, err := ioutil.TempFile("", "mantatest-input")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(input.Name())
+
+	_, err = input.Write(task.body)
+	if closeErr := input.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	script := phase.Exec
+	if phase.Init != "" {
+		script = phase.Init + "\n" + script
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("/bin/sh", "-c", script)
+	cmd.Stdin = bytes.NewReader(task.body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"MANTA_INPUT_FILE="+input.Name(),
+		"MANTA_INPUT_OBJECT="+task.input,
+	)
+
+	if err := cmd.Run(); err != nil {
+		return stderr.Bytes(), fmt.Errorf("user command exited with %v", err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// writeJobStream writes one of a job's live streams.
+func writeJobStream(w http.ResponseWriter, contentType string, size int, write func(w *bufio.Writer)) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Result-Set-Size", strconv.Itoa(size))
+	w.WriteHeader(http.StatusOK)
+
+	bw := bufio.NewWriter(w)
+	write(bw)
+	bw.Flush()
+}
@@ -0,0 +1,638 @@
+// Package mantatest provides an in-process fake of the Manta storage API for
+// use in tests.  Only the subset of Manta used by scrum is implemented:
+// objects (GET, HEAD, PUT, DELETE), SnapLinks, directories (PUT, list with
+// limit and marker, DELETE), If-Match and If-None-Match preconditions, compute
+// jobs run on the local host and HTTP signature authentication.
+package mantatest
+
+import (
+	"crypto"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	directoryContentType = "application/json; type=directory"
+	linkContentType      = "application/json; type=link"
+
+	// DefaultListLimit is the number of directory entries returned when a list
+	// request does not include a limit.  Manta's default is 256.
+	DefaultListLimit = 256
+)
+
+// Config is the configuration of a fake Manta server.
+type Config struct {
+	// Account is the Manta account whose namespace is served.  Requests outside
+	// of /Account are rejected.
+	Account string
+
+	// PublicKey is an authorized_keys(5) formatted public key.  Every request
+	// must carry a valid HTTP signature made with the matching private key.
+	PublicKey []byte
+
+	// Now returns the current time and is used for object mtimes.  Defaults to
+	// time.Now.
+	Now func() time.Time
+}
+
+// Server is a fake Manta server.
+type Server struct {
+	*httptest.Server
+
+	account string
+	keyID   string
+	pubKey  *rsa.PublicKey
+	now     func() time.Time
+
+	// lock guards the fields below
+	lock     sync.Mutex
+	nodes    map[string]*node
+	etag     uint64
+	requests map[string]uint64
+	failures map[string]*failure
+	hook     func(r *http.Request)
+	jobs     map[string]*job
+	jobID    uint64
+}
+
+// failure is an injected error response.
+type failure struct {
+	remaining  int
+	statusCode int
+	code       string
+}
+
+// node is a single object or directory.
+type node struct {
+	dir         bool
+	body        []byte
+	contentType string
+	etag        string
+	mtime       time.Time
+	headers     http.Header
+}
+
+// New starts a new fake Manta server.  The caller must call Close when done.
+func New(cfg Config) (*Server, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(cfg.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse public key: %v", err)
+	}
+
+	cryptoPub, ok := pub.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported public key type: %s", pub.Type())
+	}
+
+	rsaPub, ok := cryptoPub.CryptoPublicKey().(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported public key type: %s", pub.Type())
+	}
+
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	s := &Server{
+		account:  cfg.Account,
+		keyID:    path.Join("/", cfg.Account, "keys", md5Fingerprint(pub)),
+		pubKey:   rsaPub,
+		now:      now,
+		nodes:    make(map[string]*node),
+		requests: make(map[string]uint64),
+		failures: make(map[string]*failure),
+		jobs:     make(map[string]*job),
+	}
+
+	root := path.Join("/", cfg.Account)
+	s.nodes[root] = &node{dir: true, contentType: directoryContentType, mtime: now()}
+	s.nodes[path.Join(root, "stor")] = &node{dir: true, contentType: directoryContentType, mtime: now()}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s, nil
+}
+
+// md5Fingerprint returns the colon separated MD5 fingerprint of a key.
+func md5Fingerprint(pub ssh.PublicKey) string {
+	sum := md5.Sum(pub.Marshal())
+
+	parts := make([]string, 0, len(sum))
+	for _, b := range sum {
+		parts = append(parts, fmt.Sprintf("%02x", b))
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// Requests returns the number of requests served for the given method (e.g.
+// "GET").
+func (s *Server) Requests(method string) uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.requests[method]
+}
+
+// FailRequests causes the next n requests for objectPath, relative to the
+// account's root, to fail with the given status code and Manta error code.  A
+// negative n fails every request.
+func (s *Server) FailRequests(objectPath string, n int, statusCode int, code string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.failures[s.absPath(objectPath)] = &failure{
+		remaining:  n,
+		statusCode: statusCode,
+		code:       code,
+	}
+}
+
+// OnRequest calls fn with every authenticated request before it is handled,
+// which lets tests change the server's state at a precise point, e.g. to race
+// a put.  fn is called without any locks held.  A nil fn removes the hook.
+func (s *Server) OnRequest(fn func(r *http.Request)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.hook = fn
+}
+
+// injectedFailure returns the failure to inject for absPath, if any.
+func (s *Server) injectedFailure(absPath string) *failure {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, found := s.failures[absPath]
+	if !found || f.remaining == 0 {
+		return nil
+	}
+
+	if f.remaining > 0 {
+		f.remaining--
+	}
+
+	return f
+}
+
+// PutDirectory creates the directory dirPath, relative to the account's root,
+// and any missing parent directories.
+func (s *Server) PutDirectory(dirPath string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.mkdirAll(s.absPath(dirPath))
+}
+
+// mkdirAll must be called with lock held.
+func (s *Server) mkdirAll(absPath string) {
+	for dir := absPath; dir != "/"; dir = path.Dir(dir) {
+		if _, found := s.nodes[dir]; !found {
+			s.nodes[dir] = &node{dir: true, contentType: directoryContentType, mtime: s.now()}
+		}
+	}
+}
+
+// PutObject stores body at objectPath, relative to the account's root, and
+// creates any missing parent directories.
+func (s *Server) PutObject(objectPath string, body []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	absPath := s.absPath(objectPath)
+	s.mkdirAll(path.Dir(absPath))
+
+	s.nodes[absPath] = s.newObject(body, "application/octet-stream", http.Header{})
+}
+
+// DeleteObject removes the object at objectPath, relative to the account's
+// root.
+func (s *Server) DeleteObject(objectPath string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.nodes, s.absPath(objectPath))
+}
+
+// Object returns the contents of the object at objectPath, relative to the
+// account's root.
+func (s *Server) Object(objectPath string) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n, found := s.nodes[s.absPath(objectPath)]
+	if !found || n.dir {
+		return nil, false
+	}
+
+	return n.body, true
+}
+
+// ObjectHeaders returns the stored headers (e.g. "m-" metadata) of the object
+// at objectPath, relative to the account's root.
+func (s *Server) ObjectHeaders(objectPath string) (http.Header, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n, found := s.nodes[s.absPath(objectPath)]
+	if !found || n.dir {
+		return nil, false
+	}
+
+	return n.headers, true
+}
+
+func (s *Server) absPath(objectPath string) string {
+	return path.Join("/", s.account, objectPath)
+}
+
+// newObject must be called with lock held.
+func (s *Server) newObject(body []byte, contentType string, headers http.Header) *node {
+	s.etag++
+
+	return &node{
+		body:        body,
+		contentType: contentType,
+		etag:        strconv.FormatUint(s.etag, 16),
+		mtime:       s.now(),
+		headers:     headers,
+	}
+}
+
+var signatureRE = regexp.MustCompile(`^Signature keyId="([^"]*)",algorithm="([^"]*)",headers="([^"]*)",signature="([^"]*)"$`)
+
+// checkSignature verifies the HTTP signature of the date header.
+func (s *Server) checkSignature(r *http.Request) error {
+	md := signatureRE.FindStringSubmatch(r.Header.Get("Authorization"))
+	if md == nil {
+		return fmt.Errorf("malformed authorization header")
+	}
+	keyID, algorithm, headers, signature := md[1], md[2], md[3], md[4]
+
+	if keyID != s.keyID {
+		return fmt.Errorf("unknown key %q", keyID)
+	}
+
+	if headers != "date" {
+		return fmt.Errorf("unsupported signed headers %q", headers)
+	}
+
+	if algorithm != "rsa-sha1" {
+		return fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %v", err)
+	}
+
+	digest := sha1.Sum([]byte("date: " + r.Header.Get("Date")))
+	if err := rsa.VerifyPKCS1v15(s.pubKey, crypto.SHA1, digest[:], sig); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+
+	return nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	s.requests[r.Method]++
+	s.lock.Unlock()
+
+	if err := s.checkSignature(r); err != nil {
+		writeError(w, r, http.StatusUnauthorized, "InvalidSignature", err.Error())
+		return
+	}
+
+	absPath := path.Clean(r.URL.Path)
+	if absPath != path.Join("/", s.account) && !strings.HasPrefix(absPath, path.Join("/", s.account)+"/") {
+		writeError(w, r, http.StatusForbidden, "AuthorizationFailed", absPath+" is not in this account")
+		return
+	}
+
+	s.lock.Lock()
+	hook := s.hook
+	s.lock.Unlock()
+	if hook != nil {
+		hook(r)
+	}
+
+	if f := s.injectedFailure(absPath); f != nil {
+		writeError(w, r, f.statusCode, f.code, "injected failure")
+		return
+	}
+
+	if s.isJobPath(absPath) {
+		s.serveJobs(w, r, absPath)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.serveGet(w, r, absPath)
+	case http.MethodPut:
+		s.servePut(w, r, absPath)
+	case http.MethodDelete:
+		s.serveDelete(w, r, absPath)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "BadRequest", r.Method+" is not supported")
+	}
+}
+
+// isJobPath returns true for the job endpoints below /account/jobs, excluding
+// the objects stored by jobs.
+func (s *Server) isJobPath(absPath string) bool {
+	root := path.Join("/", s.account, "jobs")
+	if absPath != root && !strings.HasPrefix(absPath, root+"/") {
+		return false
+	}
+
+	parts := strings.Split(absPath, "/")
+	return len(parts) < 5 || parts[4] != "stor"
+}
+
+func (s *Server) serveGet(w http.ResponseWriter, r *http.Request, absPath string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n, found := s.nodes[absPath]
+	if !found {
+		writeError(w, r, http.StatusNotFound, "ResourceNotFound", absPath+" was not found")
+		return
+	}
+
+	if !n.dir {
+		for key, values := range n.headers {
+			w.Header()[key] = values
+		}
+		w.Header().Set("Content-Type", n.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(n.body)))
+		w.Header().Set("Etag", n.etag)
+		w.Header().Set("Last-Modified", n.mtime.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(n.body)
+		}
+		return
+	}
+
+	limit := DefaultListLimit
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		l, err := strconv.Atoi(rawLimit)
+		if err != nil || l < 1 {
+			writeError(w, r, http.StatusBadRequest, "InvalidLimit", "invalid limit: "+rawLimit)
+			return
+		}
+		limit = l
+	}
+
+	// Manta's list marker is named "marker", however triton-go sends it as
+	// "manta_path".
+	marker := r.URL.Query().Get("marker")
+	if marker == "" {
+		marker = r.URL.Query().Get("manta_path")
+	}
+
+	names := make([]string, 0)
+	for p := range s.nodes {
+		if p != absPath && path.Dir(p) == absPath {
+			names = append(names, path.Base(p))
+		}
+	}
+	sort.Strings(names)
+
+	type dirEntry struct {
+		Name  string `json:"name"`
+		Type  string `json:"type"`
+		MTime string `json:"mtime"`
+		ETag  string `json:"etag,omitempty"`
+		Size  *int   `json:"size,omitempty"`
+	}
+
+	entries := make([]dirEntry, 0, len(names))
+	for _, name := range names {
+		if marker != "" && name <= marker {
+			continue
+		}
+
+		if len(entries) == limit {
+			break
+		}
+
+		child := s.nodes[path.Join(absPath, name)]
+		ent := dirEntry{
+			Name:  name,
+			Type:  "directory",
+			MTime: child.mtime.UTC().Format(time.RFC3339Nano),
+		}
+		if !child.dir {
+			size := len(child.body)
+			ent.Type = "object"
+			ent.ETag = child.etag
+			ent.Size = &size
+		}
+		entries = append(entries, ent)
+	}
+
+	w.Header().Set("Content-Type", "application/x-json-stream; type=directory")
+	w.Header().Set("Result-Set-Size", strconv.Itoa(len(names)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	for _, ent := range entries {
+		enc.Encode(ent)
+	}
+}
+
+func (s *Server) servePut(w http.ResponseWriter, r *http.Request, absPath string) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parent, found := s.nodes[path.Dir(absPath)]
+	if !found {
+		writeError(w, r, http.StatusNotFound, "DirectoryDoesNotExist", path.Dir(absPath)+" does not exist")
+		return
+	}
+
+	if !parent.dir {
+		writeError(w, r, http.StatusBadRequest, "ParentNotDirectory", path.Dir(absPath)+" is not a directory")
+		return
+	}
+
+	existing, exists := s.nodes[absPath]
+
+	if r.Header.Get("Content-Type") == directoryContentType {
+		if exists && !existing.dir {
+			writeError(w, r, http.StatusBadRequest, "DirectoryOperation", absPath+" is an object")
+			return
+		}
+
+		if !exists {
+			s.nodes[absPath] = &node{dir: true, contentType: directoryContentType, mtime: s.now()}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if exists && existing.dir {
+		writeError(w, r, http.StatusBadRequest, "DirectoryOperation", absPath+" is a directory")
+		return
+	}
+
+	if !checkPreconditions(r, existing) {
+		writeError(w, r, http.StatusPreconditionFailed, "PreconditionFailed", absPath+" does not match the request preconditions")
+		return
+	}
+
+	if r.Header.Get("Content-Type") == linkContentType {
+		s.putSnapLink(w, r, absPath)
+		return
+	}
+
+	headers := http.Header{}
+	for key, values := range r.Header {
+		if strings.HasPrefix(strings.ToLower(key), "m-") {
+			headers[key] = values
+		}
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	n := s.newObject(body, contentType, headers)
+	s.nodes[absPath] = n
+
+	w.Header().Set("Etag", n.etag)
+	w.Header().Set("Last-Modified", n.mtime.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// putSnapLink creates a SnapLink at absPath to the object named by the
+// Location header.  Like Manta, the link shares the source object's contents
+// and ETag.  The caller must hold the lock.
+func (s *Server) putSnapLink(w http.ResponseWriter, r *http.Request, absPath string) {
+	srcPath := path.Clean(r.Header.Get("Location"))
+	src, found := s.nodes[srcPath]
+	switch {
+	case !found:
+		writeError(w, r, http.StatusNotFound, "SourceObjectNotFound", srcPath+" was not found")
+		return
+	case src.dir:
+		writeError(w, r, http.StatusBadRequest, "LinkNotObject", srcPath+" is a directory")
+		return
+	}
+
+	n := *src
+	n.mtime = s.now()
+	s.nodes[absPath] = &n
+
+	w.Header().Set("Etag", n.etag)
+	w.Header().Set("Last-Modified", n.mtime.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) serveDelete(w http.ResponseWriter, r *http.Request, absPath string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n, found := s.nodes[absPath]
+	if !found {
+		writeError(w, r, http.StatusNotFound, "ResourceNotFound", absPath+" was not found")
+		return
+	}
+
+	if !checkPreconditions(r, n) {
+		writeError(w, r, http.StatusPreconditionFailed, "PreconditionFailed", absPath+" does not match the request preconditions")
+		return
+	}
+
+	if n.dir {
+		for p := range s.nodes {
+			if path.Dir(p) == absPath && p != absPath {
+				writeError(w, r, http.StatusBadRequest, "DirectoryNotEmpty", absPath+" is not empty")
+				return
+			}
+		}
+	}
+
+	delete(s.nodes, absPath)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkPreconditions returns true when the If-Match and If-None-Match headers
+// of r are satisfied by the current node, n, which is nil when nothing exists.
+func checkPreconditions(r *http.Request, n *node) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if n == nil || !matchETag(ifMatch, n.etag) {
+			return false
+		}
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if n != nil && matchETag(ifNoneMatch, n.etag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchETag returns true when etag is in the comma separated list of ETags in
+// header or when header is "*".
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.Trim(strings.TrimSpace(candidate), `"`)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeError writes a Manta error response.  HEAD responses do not include a
+// body.
+func writeError(w http.ResponseWriter, r *http.Request, statusCode int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}{
+		Code:    code,
+		Message: message,
+	})
+}
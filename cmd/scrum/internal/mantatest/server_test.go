@@ -0,0 +1,228 @@
+package mantatest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gwydirsam/go-scrum/cmd/scrum/internal/mantatest"
+	triton "github.com/joyent/triton-go"
+	"github.com/joyent/triton-go/authentication"
+	tritonError "github.com/joyent/triton-go/errors"
+	"github.com/joyent/triton-go/storage"
+)
+
+const testAccount = "test"
+
+func newClient(t *testing.T, s *mantatest.Server, signer authentication.Signer) *storage.StorageClient {
+	c, err := storage.NewClient(&triton.ClientConfig{
+		MantaURL:    s.URL,
+		AccountName: testAccount,
+		Signers:     []authentication.Signer{signer},
+	})
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+
+	return c
+}
+
+func newServer(t *testing.T) (*mantatest.Server, *storage.StorageClient) {
+	s, err := mantatest.New(mantatest.Config{
+		Account:   testAccount,
+		PublicKey: authentication.Dummy.PublicKey,
+	})
+	if err != nil {
+		t.Fatalf("unable to start server: %v", err)
+	}
+
+	signer, err := authentication.NewPrivateKeySigner(authentication.PrivateKeySignerInput{
+		KeyID:              authentication.Dummy.Fingerprint,
+		PrivateKeyMaterial: authentication.Dummy.PrivateKey,
+		AccountName:        testAccount,
+	})
+	if err != nil {
+		t.Fatalf("unable to create signer: %v", err)
+	}
+
+	return s, newClient(t, s, signer)
+}
+
+func TestRejectsBadSignature(t *testing.T) {
+	s, _ := newServer(t)
+	defer s.Close()
+
+	c := newClient(t, s, authentication.Dummy.Signer)
+	_, err := c.Dir().List(context.Background(), &storage.ListDirectoryInput{
+		DirectoryName: "stor",
+	})
+	if !tritonError.IsInvalidSignatureError(err) {
+		t.Fatalf("error = %v, want InvalidSignature", err)
+	}
+}
+
+func TestListDirectoryPaging(t *testing.T) {
+	s, c := newServer(t)
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		s.PutObject(fmt.Sprintf("stor/dir/obj%d", i), []byte("x"))
+	}
+
+	var names []string
+	var marker string
+	for {
+		out, err := c.Dir().List(context.Background(), &storage.ListDirectoryInput{
+			DirectoryName: "stor/dir",
+			Limit:         2,
+			Marker:        marker,
+		})
+		if err != nil {
+			t.Fatalf("unable to list directory: %v", err)
+		}
+
+		if out.ResultSetSize != 5 {
+			t.Errorf("result set size = %d, want 5", out.ResultSetSize)
+		}
+
+		if len(out.Entries) == 0 {
+			break
+		}
+
+		for _, ent := range out.Entries {
+			names = append(names, ent.Name)
+		}
+		marker = out.Entries[len(out.Entries)-1].Name
+	}
+
+	if got, want := fmt.Sprint(names), "[obj0 obj1 obj2 obj3 obj4]"; got != want {
+		t.Errorf("names = %s, want %s", got, want)
+	}
+}
+
+func TestPutRequiresParentDirectory(t *testing.T) {
+	s, c := newServer(t)
+	defer s.Close()
+
+	ctx := context.Background()
+	err := c.Objects().Put(ctx, &storage.PutObjectInput{
+		ObjectPath: "stor/missing/obj",
+	})
+	if !tritonError.IsDirectoryDoesNotExistError(err) {
+		t.Fatalf("error = %v, want DirectoryDoesNotExist", err)
+	}
+
+	if err := c.Dir().Put(ctx, &storage.PutDirectoryInput{DirectoryName: "stor/missing"}); err != nil {
+		t.Fatalf("unable to put directory: %v", err)
+	}
+
+	if err := c.Objects().Put(ctx, &storage.PutObjectInput{ObjectPath: "stor/missing/obj"}); err != nil {
+		t.Fatalf("unable to put object: %v", err)
+	}
+
+	if err := c.Objects().Delete(ctx, &storage.DeleteObjectInput{ObjectPath: "stor/missing/obj"}); err != nil {
+		t.Fatalf("unable to delete object: %v", err)
+	}
+
+	if _, found := s.Object("stor/missing/obj"); found {
+		t.Errorf("object was not deleted")
+	}
+}
+
+func TestPutPreconditions(t *testing.T) {
+	s, c := newServer(t)
+	defer s.Close()
+
+	ctx := context.Background()
+	s.PutObject("stor/obj", []byte("x"))
+
+	err := c.Objects().Put(ctx, &storage.PutObjectInput{
+		ObjectPath: "stor/obj",
+		Headers:    map[string]string{"If-None-Match": "*"},
+	})
+	if !tritonError.IsPreconditionFailedError(err) {
+		t.Fatalf("error = %v, want PreconditionFailed", err)
+	}
+
+	err = c.Objects().Put(ctx, &storage.PutObjectInput{
+		ObjectPath: "stor/obj",
+		IfMatch:    "bogus",
+	})
+	if !tritonError.IsPreconditionFailedError(err) {
+		t.Fatalf("error = %v, want PreconditionFailed", err)
+	}
+
+	info, err := c.Objects().GetInfo(ctx, &storage.GetInfoInput{ObjectPath: "stor/obj"})
+	if err != nil {
+		t.Fatalf("unable to get info: %v", err)
+	}
+
+	err = c.Objects().Put(ctx, &storage.PutObjectInput{
+		ObjectPath: "stor/obj",
+		IfMatch:    info.ETag,
+	})
+	if err != nil {
+		t.Fatalf("unable to put object: %v", err)
+	}
+}
+
+func TestJob(t *testing.T) {
+	s, c := newServer(t)
+	defer s.Close()
+
+	ctx := context.Background()
+	s.PutObject("stor/a", []byte("one\ntwo\n"))
+	s.PutObject("stor/b", []byte("three\n"))
+
+	job, err := c.Jobs().Create(ctx, &storage.CreateJobInput{
+		Name: "test",
+		Phases: []*storage.JobPhase{
+			{Type: "map", Exec: "grep -v two"},
+			{Type: "reduce", Exec: "sort"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to create job: %v", err)
+	}
+
+	err = c.Jobs().AddInputs(ctx, &storage.AddJobInputsInput{
+		JobID:       job.JobID,
+		ObjectPaths: []string{"/" + testAccount + "/stor/a", "/" + testAccount + "/stor/b", "/" + testAccount + "/stor/missing"},
+	})
+	if err != nil {
+		t.Fatalf("unable to add inputs: %v", err)
+	}
+
+	if err := c.Jobs().EndInput(ctx, &storage.EndJobInputInput{JobID: job.JobID}); err != nil {
+		t.Fatalf("unable to end input: %v", err)
+	}
+
+	status, err := c.Jobs().Get(ctx, &storage.GetJobInput{JobID: job.JobID})
+	if err != nil {
+		t.Fatalf("unable to get job: %v", err)
+	}
+
+	if status.Job.State != storage.JobStateDone {
+		t.Errorf("state = %q, want %q", status.Job.State, storage.JobStateDone)
+	}
+
+	if got, want := status.Job.Stats.Errors, uint64(1); got != want {
+		t.Errorf("errors = %d, want %d", got, want)
+	}
+
+	if got, want := status.Job.Stats.Outputs, uint64(1); got != want {
+		t.Fatalf("outputs = %d, want %d", got, want)
+	}
+
+	// GetOutput closes the output stream before returning it, so find the
+	// output object by listing the job's directory.
+	dir, err := c.Dir().List(ctx, &storage.ListDirectoryInput{DirectoryName: "jobs/" + job.JobID + "/stor"})
+	if err != nil || len(dir.Entries) != 1 {
+		t.Fatalf("unable to list job outputs: %v", err)
+	}
+
+	body, found := s.Object("jobs/" + job.JobID + "/stor/" + dir.Entries[0].Name)
+	if got, want := string(body), "one\nthree\n"; !found || got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
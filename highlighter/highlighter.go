@@ -33,6 +33,22 @@ type TokenColor struct {
 	Color *color.Color
 }
 
+// Match returns true if tok matches the TokenColor's Token: as a
+// case-insensitive substring when Submatch is set, within the
+// Damerau–Levenshtein Distance when Distance is greater than 0, or as a
+// case-insensitive exact match otherwise.
+func (t *TokenColor) Match(tok string) bool {
+	switch {
+	case t.Submatch:
+		return strings.Contains(strings.ToLower(tok), strings.ToLower(t.Token))
+	case t.Distance > 0:
+		dist := textdistance.DamerauLevenshteinDistance(strings.ToLower(tok), strings.ToLower(t.Token))
+		return dist <= t.Distance
+	default:
+		return strings.ToLower(tok) == strings.ToLower(t.Token)
+	}
+}
+
 type Highlighter struct {
 	w    io.Writer
 	d    int
@@ -152,31 +168,11 @@ func (h *Highlighter) Write(p []byte) (n int, err error) {
 		for tokS.Scan() {
 			tok := tokS.Text()
 			for _, t := range h.toks {
-				switch {
-				case t.Submatch:
-					if strings.Contains(strings.ToLower(tok), strings.ToLower(t.Token)) {
-						replacements = append(replacements, replacement{
-							old: tok,
-							new: t,
-						})
-					}
-
-				case t.Distance == 0:
-					if strings.ToLower(tok) == strings.ToLower(t.Token) {
-						replacements = append(replacements, replacement{
-							old: tok,
-							new: t,
-						})
-					}
-
-				case t.Distance > 0:
-					dist := textdistance.DamerauLevenshteinDistance(strings.ToLower(tok), strings.ToLower(t.Token))
-					if dist <= t.Distance {
-						replacements = append(replacements, replacement{
-							old: tok,
-							new: t,
-						})
-					}
+				if t.Match(tok) {
+					replacements = append(replacements, replacement{
+						old: tok,
+						new: t,
+					})
 				}
 			}
 		}